@@ -0,0 +1,9 @@
+# Discover the disks that are not attached to any instance
+list "lima_disk" "detached" {
+  provider         = lima
+  include_resource = true
+
+  config {
+    instance = ""
+  }
+}
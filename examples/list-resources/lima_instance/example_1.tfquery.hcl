@@ -0,0 +1,12 @@
+# Discover the running development instances created with limactl, then
+# generate configuration and import blocks for them with
+#   terraform query -generate-config-out=generated.tf
+list "lima_instance" "dev" {
+  provider         = lima
+  include_resource = true
+
+  config {
+    name_pattern = "dev-*"
+    status       = "Running"
+  }
+}
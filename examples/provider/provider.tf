@@ -1,4 +1,46 @@
 provider "lima" {
-  # No provider configuration required
+  # All settings are optional.
   # Be sure to have Lima installed: https://lima-vm.io/
 }
+
+# Isolated Lima home, for example for CI sandboxes next to developer VMs
+provider "lima" {
+  alias          = "ci"
+  limactl_path   = "/opt/homebrew/bin/limactl"
+  lima_home      = "~/.lima-ci"
+  audit_log_path = "~/.lima-ci/terraform-audit.jsonl"
+
+  env = {
+    HTTPS_PROXY = "http://proxy.internal:3128"
+  }
+}
+
+# Shared settings for every instance of this provider
+provider "lima" {
+  alias                 = "team"
+  max_memory_overcommit = 1.5
+  workspace             = "team-vms/${terraform.workspace}"
+
+  defaults = {
+    vm_type    = "vz"
+    mount_type = "virtiofs"
+    containerd = "none"
+    cpus       = 4
+    memory     = 8
+    dns        = ["1.1.1.1"]
+  }
+}
+
+# VMs on a shared build machine, named alice-<name> so that they do not
+# collide with the instances of other users
+provider "lima" {
+  alias        = "buildbox"
+  limactl_path = "/opt/homebrew/bin/limactl"
+  name_prefix  = "alice-"
+
+  ssh = {
+    host        = "buildbox.internal"
+    user        = "ci"
+    private_key = file("~/.ssh/id_ed25519")
+  }
+}
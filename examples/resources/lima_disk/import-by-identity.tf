@@ -0,0 +1,10 @@
+import {
+  to = lima_disk.data
+  identity = {
+    name = "data"
+
+    # Optional, they default to the store the provider is configured for
+    lima_home = "/Users/me/.lima"
+    host      = "localhost"
+  }
+}
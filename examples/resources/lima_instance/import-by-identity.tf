@@ -0,0 +1,10 @@
+import {
+  to = lima_instance.dev
+  identity = {
+    name = "dev"
+
+    # Optional, they default to the store the provider is configured for
+    lima_home = "/Users/me/.lima"
+    host      = "localhost"
+  }
+}
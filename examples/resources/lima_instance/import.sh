@@ -9,7 +9,8 @@
 resource "lima_instance" "existing" {
   name = "my-existing-instance"
   # Add other known configuration attributes
-  # Note: Some attributes may not be fully recoverable during import
+  # Attributes left unset take the values of the instance. The template and
+  # mount_none are only known for instances created by this provider.
 }
 
 # Then import the instance:
@@ -17,3 +18,6 @@ resource "lima_instance" "existing" {
 
 # After import, run terraform plan to see if there are any differences
 # between your configuration and the actual instance state.
+
+# To import many instances at once, use the lima_instance list resource with
+# terraform query -generate-config-out=generated.tf (Terraform v1.14 and later).
@@ -48,6 +48,12 @@ resource "lima_instance" "plain" {
   memory = 2
 }
 
+# Lima instance that is created, and kept, stopped
+resource "lima_instance" "stopped" {
+  name    = "stopped"
+  running = false
+}
+
 # Lima instance from remote URL (use with caution)
 resource "lima_instance" "remote" {
   name     = "alpine"
@@ -0,0 +1,131 @@
+package limactl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Operations recorded in the audit log.
+const (
+	OperationConfigure = "configure"
+	OperationCreate    = "create"
+	OperationRead      = "read"
+	OperationUpdate    = "update"
+	OperationDelete    = "delete"
+	OperationImport    = "import"
+)
+
+// auditOutputLimit is how much of the command output is kept per entry. The
+// end of the output is kept because that is where limactl reports errors.
+const auditOutputLimit = 4096
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	// Resource identifies the Terraform resource as "<type>.<name>" using the
+	// Lima object name, because providers do not see configuration addresses.
+	Resource        string            `json:"resource,omitempty"`
+	Operation       string            `json:"operation,omitempty"`
+	Argv            []string          `json:"argv"`
+	Env             map[string]string `json:"env,omitempty"`
+	DryRun          bool              `json:"dry_run,omitempty"`
+	ExitCode        int               `json:"exit_code"`
+	DurationMS      int64             `json:"duration_ms"`
+	Output          string            `json:"output,omitempty"`
+	OutputTruncated bool              `json:"output_truncated,omitempty"`
+}
+
+// AuditLog appends AuditEntry values as JSON lines to a file. The file is
+// opened for each entry, so that no handle outlives the provider
+// configuration that created the log.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+// OpenAuditLog checks that path can be appended to, creating it if needed.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{path: path}, nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+}
+
+// Write appends entry to the log.
+func (l *AuditLog) Write(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := openAppend(l.path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+type operationKey struct{}
+
+type operation struct {
+	resource string
+	action   string
+}
+
+// WithOperation returns a context whose limactl invocations are attributed to
+// the resource and operation in the audit log.
+func WithOperation(ctx context.Context, resource string, action string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation{resource: resource, action: action})
+}
+
+func operationFrom(ctx context.Context) operation {
+	op, _ := ctx.Value(operationKey{}).(operation)
+	return op
+}
+
+// ExitCode returns the exit code carried by err: 0 for nil and -1 when the
+// command did not exit normally.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
+
+func truncateOutput(output []byte) (string, bool) {
+	if len(output) <= auditOutputLimit {
+		return string(output), false
+	}
+
+	return string(output[len(output)-auditOutputLimit:]), true
+}
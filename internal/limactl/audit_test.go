@@ -0,0 +1,109 @@
+package limactl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	script := writeScript(t, `
+if [ "$1" = "stop" ]; then
+	echo "instance is broken" >&2
+	exit 3
+fi
+head -c 5000 /dev/zero | tr '\0' x
+`)
+
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := OpenAuditLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := New(Config{
+		Path:     script,
+		LimaHome: "/tmp/lima-home",
+		AuditLog: auditLog,
+	})
+
+	ctx := WithOperation(context.Background(), "lima_instance.dev", OperationUpdate)
+
+	if _, err := client.Run(ctx, "list", "--json"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := client.StopInstance(ctx, "dev"); err == nil {
+		t.Fatal("expected stop to fail")
+	}
+
+	entries := readAuditLog(t, logPath)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	list := entries[0]
+	if list.Resource != "lima_instance.dev" || list.Operation != OperationUpdate || list.ExitCode != 0 {
+		t.Errorf("unexpected entry: %+v", list)
+	}
+	if strings.Join(list.Argv, " ") != script+" list --json" {
+		t.Errorf("unexpected argv: %q", list.Argv)
+	}
+	if list.Env["LIMA_HOME"] != "/tmp/lima-home" {
+		t.Errorf("unexpected env: %v", list.Env)
+	}
+	if !list.OutputTruncated || len(list.Output) != auditOutputLimit {
+		t.Errorf("expected output truncated to %d bytes, got %d (truncated %t)", auditOutputLimit, len(list.Output), list.OutputTruncated)
+	}
+
+	stop := entries[1]
+	if stop.ExitCode != 3 || !strings.Contains(stop.Output, "instance is broken") || stop.OutputTruncated {
+		t.Errorf("unexpected entry: %+v", stop)
+	}
+}
+
+func TestAuditLogDryRun(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := OpenAuditLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := New(Config{Path: "/nonexistent/limactl", DryRun: true, AuditLog: auditLog})
+
+	ctx := WithOperation(context.Background(), "lima_disk.data", OperationDelete)
+	if err := client.DeleteDisk(ctx, "data"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entries := readAuditLog(t, logPath)
+	if len(entries) != 1 || !entries[0].DryRun || entries[0].Operation != OperationDelete {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func readAuditLog(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %s", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
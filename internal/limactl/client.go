@@ -0,0 +1,409 @@
+// Package limactl provides a typed client for the limactl command line tool.
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ErrNotFound is returned when a Lima instance or disk does not exist.
+var ErrNotFound = errors.New("not found")
+
+// DefaultInterruptGracePeriod is how long an interrupted limactl command may
+// take to exit before it is killed.
+const DefaultInterruptGracePeriod = 30 * time.Second
+
+// Config describes how limactl is invoked.
+type Config struct {
+	// Path is the limactl binary. Defaults to "limactl".
+	Path string
+
+	// LimaHome overrides LIMA_HOME when non-empty.
+	LimaHome string
+
+	// Env holds extra environment variables for every invocation.
+	Env map[string]string
+
+	// InventoryTTL is how long list results are cached. Defaults to
+	// DefaultInventoryTTL.
+	InventoryTTL time.Duration
+
+	// Transport runs the commands. Defaults to LocalTransport.
+	Transport Transport
+
+	// Retry controls how transient failures of idempotent commands are
+	// retried. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// InterruptGracePeriod is how long limactl may take to exit after it
+	// was interrupted before it is killed. Defaults to
+	// DefaultInterruptGracePeriod.
+	InterruptGracePeriod time.Duration
+
+	// AuditLog receives an entry for every limactl invocation when set.
+	AuditLog *AuditLog
+
+	// DryRun skips every command that would change instances or disks and
+	// records its command line instead.
+	DryRun bool
+
+	// ReadFromStore lists instances and disks by reading LIMA_HOME directly
+	// instead of running limactl, falling back to limactl when the layout
+	// is not recognised or the host capacity cannot be read.
+	ReadFromStore bool
+}
+
+// Client runs limactl commands and decodes their output.
+type Client struct {
+	path     string
+	limaHome string
+	env      map[string]string
+	version  *version.Version
+
+	transport   Transport
+	gracePeriod time.Duration
+	retry       RetryPolicy
+	dryRun      bool
+	auditLog    *AuditLog
+	inventory   *Inventory
+	store       *Store
+}
+
+// New returns a client for the given configuration.
+func New(cfg Config) *Client {
+	path := cfg.Path
+	if path == "" {
+		path = "limactl"
+	}
+
+	ttl := cfg.InventoryTTL
+	if ttl == 0 {
+		ttl = DefaultInventoryTTL
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = LocalTransport{}
+	}
+
+	gracePeriod := cfg.InterruptGracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = DefaultInterruptGracePeriod
+	}
+
+	retry := DefaultRetryPolicy
+	if cfg.Retry != nil {
+		retry = *cfg.Retry
+	}
+
+	c := &Client{
+		path:        path,
+		limaHome:    cfg.LimaHome,
+		env:         cfg.Env,
+		transport:   transport,
+		gracePeriod: gracePeriod,
+		retry:       retry,
+		dryRun:      cfg.DryRun,
+		auditLog:    cfg.AuditLog,
+	}
+	c.inventory = newInventory(c, ttl)
+
+	if cfg.ReadFromStore {
+		if home, err := c.resolveLimaHome(); err == nil {
+			c.store = NewStore(home)
+		}
+	}
+
+	return c
+}
+
+// Path returns the limactl binary used by the client.
+func (c *Client) Path() string {
+	return c.path
+}
+
+// LimaHome returns the configured LIMA_HOME, or an empty string when the
+// environment default is used.
+func (c *Client) LimaHome() string {
+	return c.limaHome
+}
+
+// DryRun reports whether mutating commands are skipped.
+func (c *Client) DryRun() bool {
+	return c.dryRun
+}
+
+// Inventory returns the client's cached view of instances and disks.
+func (c *Client) Inventory() *Inventory {
+	return c.inventory
+}
+
+// CommandError is returned when limactl exits unsuccessfully.
+type CommandError struct {
+	Args   []string
+	Output []byte
+	Err    error
+
+	// Messages are the error-level log messages limactl reported, when it
+	// was run with JSON logging.
+	Messages []string
+}
+
+func (e *CommandError) Error() string {
+	if len(e.Messages) > 0 {
+		return fmt.Sprintf("Command: limactl %s\nError: %s", strings.Join(e.Args, " "), strings.Join(e.Messages, "\n"))
+	}
+
+	return fmt.Sprintf("Command: limactl %s\nError: %s\nOutput: %s", strings.Join(e.Args, " "), e.Err, string(e.Output))
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes limactl with args and returns its standard output. On failure
+// the returned *CommandError carries the combined stdout and stderr.
+func (c *Client) Run(ctx context.Context, args ...string) ([]byte, error) {
+	tflog.Debug(ctx, "Running limactl", map[string]any{
+		"command": "limactl " + strings.Join(args, " "),
+	})
+
+	argv := []string{c.path}
+	if c.jsonLogs() {
+		argv = append(argv, "--log-format=json")
+	}
+	argv = append(argv, args...)
+
+	var stdout bytes.Buffer
+	combined := &lockedBuffer{}
+	logs := newLogWriter(ctx)
+	start := time.Now()
+
+	process, err := c.transport.Start(ctx, &Command{
+		Args:   argv,
+		Env:    c.commandEnv(),
+		Stdout: io.MultiWriter(&stdout, combined),
+		Stderr: io.MultiWriter(combined, logs),
+	})
+	if err == nil {
+		err = c.wait(ctx, process)
+	}
+	_ = logs.Close()
+
+	c.audit(ctx, argv, start, combined.Bytes(), err, false)
+
+	if err != nil {
+		return nil, &CommandError{
+			Args:     args,
+			Output:   combined.Bytes(),
+			Err:      err,
+			Messages: logs.Messages(),
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// wait waits for process to exit. When ctx is cancelled first, limactl gets
+// SIGINT so that it can release its locks and remove what it created, and is
+// killed if it is still running after the grace period. The returned error
+// then wraps ctx.Err().
+func (c *Client) wait(ctx context.Context, process Process) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- process.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	tflog.Warn(ctx, "Interrupting limactl", map[string]any{
+		"grace_period": c.gracePeriod.String(),
+	})
+
+	// Windows cannot deliver SIGINT to another process.
+	if err := process.Signal(os.Interrupt); err != nil {
+		_ = process.Signal(os.Kill)
+	}
+
+	timer := time.NewTimer(c.gracePeriod)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-timer.C:
+		tflog.Warn(ctx, "limactl did not exit within the grace period, killing it")
+		_ = process.Signal(os.Kill)
+		err = <-done
+	}
+
+	if err == nil {
+		return fmt.Errorf("interrupted: %w", ctx.Err())
+	}
+
+	return fmt.Errorf("interrupted: %w: %w", ctx.Err(), err)
+}
+
+// audit records an invocation in the audit log, if one is configured.
+// Failing to write the log is reported but never fails the operation.
+func (c *Client) audit(ctx context.Context, argv []string, start time.Time, output []byte, err error, dryRun bool) {
+	if c.auditLog == nil {
+		return
+	}
+
+	op := operationFrom(ctx)
+	out, truncated := truncateOutput(output)
+
+	entry := AuditEntry{
+		Timestamp:       start.UTC(),
+		Resource:        op.resource,
+		Operation:       op.action,
+		Argv:            argv,
+		Env:             c.commandEnv(),
+		DryRun:          dryRun,
+		ExitCode:        ExitCode(err),
+		DurationMS:      time.Since(start).Milliseconds(),
+		Output:          out,
+		OutputTruncated: truncated,
+	}
+
+	if err := c.auditLog.Write(entry); err != nil {
+		tflog.Error(ctx, "Failed to write audit log", map[string]any{
+			"error": err.Error(),
+		})
+	}
+}
+
+// mutate runs a command that changes instances or disks and invalidates the
+// inventory, whether or not the command succeeded. In dry-run mode the
+// command line is recorded instead.
+func (c *Client) mutate(ctx context.Context, args ...string) error {
+	defer c.inventory.Invalidate()
+
+	if c.dryRun {
+		line := c.CommandLine(args...)
+
+		tflog.Warn(ctx, "Dry run, not running limactl", map[string]any{
+			"command": line,
+		})
+
+		if rec := recorderFrom(ctx); rec != nil {
+			rec.add(line)
+		}
+
+		c.audit(ctx, append([]string{c.path}, args...), time.Now(), nil, nil, true)
+
+		return nil
+	}
+
+	_, err := c.Run(ctx, args...)
+	return err
+}
+
+// CommandLine renders the shell command line that runs limactl with args,
+// including the environment the client sets.
+func (c *Client) CommandLine(args ...string) string {
+	line := CommandLine(append([]string{c.path}, args...))
+
+	if env := c.commandEnv(); len(env) > 0 {
+		line = envAssignments(env) + " " + line
+	}
+
+	return line
+}
+
+// Home returns the LIMA_HOME limactl uses, which identifies the Lima store the
+// client works on. On a remote host without a configured LIMA_HOME it is
+// "~/.lima", as the remote home directory is not known.
+func (c *Client) Home() string {
+	if _, ok := c.transport.(LocalTransport); !ok {
+		if c.limaHome != "" {
+			return c.limaHome
+		}
+
+		if home := c.env["LIMA_HOME"]; home != "" {
+			return home
+		}
+
+		return "~/.lima"
+	}
+
+	home, err := c.resolveLimaHome()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Clean(home)
+}
+
+// resolveLimaHome returns the LIMA_HOME limactl uses, following the same
+// precedence as the environment passed to it.
+func (c *Client) resolveLimaHome() (string, error) {
+	if c.limaHome != "" {
+		return c.limaHome, nil
+	}
+
+	if home := c.env["LIMA_HOME"]; home != "" {
+		return home, nil
+	}
+
+	if home := os.Getenv("LIMA_HOME"); home != "" {
+		return home, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".lima"), nil
+}
+
+// commandEnv returns the variables set for every limactl invocation.
+func (c *Client) commandEnv() map[string]string {
+	env := map[string]string{}
+
+	for k, v := range c.env {
+		env[k] = v
+	}
+
+	if c.limaHome != "" {
+		env["LIMA_HOME"] = c.limaHome
+	}
+
+	return env
+}
+
+// lockedBuffer is a bytes.Buffer that is safe to share between the stdout and
+// stderr copying goroutines of exec.Cmd.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
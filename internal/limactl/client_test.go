@@ -0,0 +1,224 @@
+package limactl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript creates an executable shell script that stands in for limactl.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "limactl")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestClientListInstances(t *testing.T) {
+	path := writeScript(t, `
+echo "warning on stderr" >&2
+echo '{"name":"dev","status":"Running","vmType":"vz","arch":"aarch64","cpus":4,"memory":4294967296,"disk":107374182400,"dir":"/lima/dev","sshLocalPort":60022}'
+echo '{"name":"ci","status":"Stopped","vmType":"qemu","arch":"x86_64","cpus":2}'
+`)
+
+	client := New(Config{Path: path})
+
+	instances, err := client.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(instances))
+	}
+
+	dev := instances[0]
+	if dev.Name != "dev" || dev.Status != StatusRunning || dev.VMType != "vz" || dev.CPUs != 4 || dev.Memory != 4<<30 || dev.SSHLocalPort != 60022 {
+		t.Errorf("unexpected instance: %+v", dev)
+	}
+
+	if _, err := client.GetInstance(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClientEnvironment(t *testing.T) {
+	path := writeScript(t, `echo "{\"name\":\"$LIMA_HOME\",\"format\":\"$EXTRA\"}"`)
+
+	client := New(Config{
+		Path:     path,
+		LimaHome: "/tmp/lima-home",
+		Env:      map[string]string{"EXTRA": "value"},
+	})
+
+	disks, err := client.ListDisks(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(disks) != 1 || disks[0].Name != "/tmp/lima-home" || disks[0].Format != "value" {
+		t.Errorf("unexpected disks: %+v", disks)
+	}
+}
+
+func TestClientHome(t *testing.T) {
+	t.Setenv("LIMA_HOME", "/tmp/env-lima/")
+
+	remote := NewSSHTransport(SSHConfig{Host: "buildbox.internal"})
+
+	tests := map[string]struct {
+		config Config
+		want   string
+	}{
+		"local configured": {Config{LimaHome: "/tmp/lima-home/"}, "/tmp/lima-home"},
+		"local env":        {Config{Env: map[string]string{"LIMA_HOME": "/tmp/extra"}}, "/tmp/extra"},
+		"local default":    {Config{}, "/tmp/env-lima"},
+		"remote":           {Config{Transport: remote, LimaHome: "/srv/lima"}, "/srv/lima"},
+		"remote default":   {Config{Transport: remote}, "~/.lima"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := New(tt.config).Home(); got != tt.want {
+				t.Errorf("Home() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCommandError(t *testing.T) {
+	path := writeScript(t, `echo "instance \"dev\" already exists" >&2; exit 1`)
+
+	client := New(Config{Path: path})
+
+	err := client.CreateInstance(context.Background(), "dev", "template://docker", []string{"--cpus=2"})
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected *CommandError, got %v", err)
+	}
+
+	if got := strings.Join(cmdErr.Args, " "); got != "create --name=dev --cpus=2 --tty=false template://docker" {
+		t.Errorf("unexpected args: %s", got)
+	}
+
+	if !strings.Contains(string(cmdErr.Output), "already exists") {
+		t.Errorf("expected output in error, got %q", cmdErr.Output)
+	}
+}
+
+func TestClientDryRun(t *testing.T) {
+	client := New(Config{
+		Path:     writeScript(t, `echo "should not run" >&2; exit 1`),
+		LimaHome: "/tmp/lima home",
+		DryRun:   true,
+	})
+
+	ctx, rec := WithRecorder(context.Background())
+
+	if err := client.CreateInstance(ctx, "dev", "template://docker", []string{`--set=.additionalDisks=[{"name":"data"}]`}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := client.StartInstance(ctx, "dev"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	commands := rec.Commands()
+	if len(commands) != 2 {
+		t.Fatalf("expected 2 recorded commands, got %v", commands)
+	}
+
+	want := `'LIMA_HOME=/tmp/lima home' ` + client.Path() + ` create --name=dev '--set=.additionalDisks=[{"name":"data"}]' --tty=false template://docker`
+	if commands[0] != want {
+		t.Errorf("unexpected command line:\n got: %s\nwant: %s", commands[0], want)
+	}
+}
+
+func TestClientInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	interrupted := filepath.Join(dir, "interrupted")
+
+	path := writeScript(t, `
+trap 'touch `+interrupted+`; exit 130' INT
+touch `+started+`
+while true; do sleep 0.05; done
+`)
+
+	client := New(Config{Path: path})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancelOnFile(cancel, started)
+
+	err := client.StartInstance(ctx, "dev")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if _, err := os.Stat(interrupted); err != nil {
+		t.Errorf("limactl was not interrupted with SIGINT: %s", err)
+	}
+}
+
+func TestClientInterruptGracePeriod(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+
+	path := writeScript(t, `
+trap '' INT
+touch `+started+`
+exec sleep 30
+`)
+
+	client := New(Config{Path: path, InterruptGracePeriod: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancelOnFile(cancel, started)
+
+	begin := time.Now()
+	err := client.StartInstance(ctx, "dev")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if elapsed := time.Since(begin); elapsed > 10*time.Second {
+		t.Errorf("limactl was not killed after the grace period, took %s", elapsed)
+	}
+}
+
+// cancelOnFile calls cancel once path exists.
+func cancelOnFile(cancel context.CancelFunc, path string) {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			cancel()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientStartTimeout(t *testing.T) {
+	path := writeScript(t, `echo "$@" >&2; exit 1`)
+	client := New(Config{Path: path, Retry: &RetryPolicy{MaxAttempts: 1}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	var cmdErr *CommandError
+	if err := client.StartInstance(ctx, "dev"); !errors.As(err, &cmdErr) {
+		t.Fatalf("expected CommandError, got %v", err)
+	}
+
+	if args := strings.TrimSpace(string(cmdErr.Output)); args != "start dev --timeout=1m30s" {
+		t.Errorf("unexpected arguments: %q", args)
+	}
+}
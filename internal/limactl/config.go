@@ -0,0 +1,92 @@
+package limactl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InstanceConfig is the subset of lima.yaml the provider understands. Lima
+// uses the same field names for YAML and for the `config` object printed by
+// `limactl list --json`.
+type InstanceConfig struct {
+	VMType          *string          `yaml:"vmType,omitempty" json:"vmType,omitempty"`
+	Arch            *string          `yaml:"arch,omitempty" json:"arch,omitempty"`
+	CPUs            *int             `yaml:"cpus,omitempty" json:"cpus,omitempty"`
+	Memory          *string          `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Disk            *string          `yaml:"disk,omitempty" json:"disk,omitempty"`
+	AdditionalDisks []AdditionalDisk `yaml:"additionalDisks,omitempty" json:"additionalDisks,omitempty"`
+	Mounts          []Mount          `yaml:"mounts,omitempty" json:"mounts,omitempty"`
+	MountType       *string          `yaml:"mountType,omitempty" json:"mountType,omitempty"`
+	MountInotify    *bool            `yaml:"mountInotify,omitempty" json:"mountInotify,omitempty"`
+	Containerd      Containerd       `yaml:"containerd,omitempty" json:"containerd,omitempty"`
+	Networks        []Network        `yaml:"networks,omitempty" json:"networks,omitempty"`
+	DNS             []string         `yaml:"dns,omitempty" json:"dns,omitempty"`
+	Video           Video            `yaml:"video,omitempty" json:"video,omitempty"`
+	Rosetta         Rosetta          `yaml:"rosetta,omitempty" json:"rosetta,omitempty"`
+	Plain           *bool            `yaml:"plain,omitempty" json:"plain,omitempty"`
+	SSH             SSH              `yaml:"ssh,omitempty" json:"ssh,omitempty"`
+}
+
+// Mount is an entry of the mounts list.
+type Mount struct {
+	Location   string  `yaml:"location" json:"location"`
+	MountPoint *string `yaml:"mountPoint,omitempty" json:"mountPoint,omitempty"`
+	Writable   *bool   `yaml:"writable,omitempty" json:"writable,omitempty"`
+}
+
+// Containerd selects the containerd flavours started in the guest.
+type Containerd struct {
+	System *bool `yaml:"system,omitempty" json:"system,omitempty"`
+	User   *bool `yaml:"user,omitempty" json:"user,omitempty"`
+}
+
+// Video configures the display of the instance.
+type Video struct {
+	Display *string `yaml:"display,omitempty" json:"display,omitempty"`
+}
+
+// Rosetta configures Rosetta for vz instances.
+type Rosetta struct {
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	BinFmt  *bool `yaml:"binfmt,omitempty" json:"binfmt,omitempty"`
+}
+
+// SSH configures the SSH port forward of the instance.
+type SSH struct {
+	LocalPort *int `yaml:"localPort,omitempty" json:"localPort,omitempty"`
+}
+
+// UnmarshalYAML accepts both the short form (a disk name) and the full object.
+func (d *AdditionalDisk) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*d = AdditionalDisk{Name: node.Value}
+		return nil
+	}
+
+	type plain AdditionalDisk
+	return node.Decode((*plain)(d))
+}
+
+// UnmarshalJSON accepts both the short form (a disk name) and the full object.
+func (d *AdditionalDisk) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*d = AdditionalDisk{Name: name}
+		return nil
+	}
+
+	type plain AdditionalDisk
+	return json.Unmarshal(data, (*plain)(d))
+}
+
+// ParseConfig decodes lima.yaml.
+func ParseConfig(data []byte) (*InstanceConfig, error) {
+	var cfg InstanceConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse lima.yaml: %w", err)
+	}
+
+	return &cfg, nil
+}
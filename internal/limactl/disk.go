@@ -0,0 +1,84 @@
+package limactl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Disk is one entry of `limactl disk list --json`.
+type Disk struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"` // bytes
+	Format      string `json:"format"`
+	Dir         string `json:"dir"`
+	Instance    string `json:"instance"`
+	InstanceDir string `json:"instanceDir"`
+	MountPoint  string `json:"mountPoint"`
+}
+
+// ListDisks returns every disk in LIMA_HOME.
+func (c *Client) ListDisks(ctx context.Context) ([]Disk, error) {
+	if c.store != nil {
+		disks, err := c.store.ListDisks()
+		if err == nil {
+			return disks, nil
+		}
+
+		tflog.Debug(ctx, "Reading LIMA_HOME failed, falling back to limactl disk list", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	output, err := c.Run(ctx, "disk", "list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []Disk
+	if err := decodeLines(output, &disks); err != nil {
+		return nil, fmt.Errorf("failed to parse disk list JSON: %w", err)
+	}
+
+	return disks, nil
+}
+
+// GetDisk returns the named disk, or ErrNotFound.
+func (c *Client) GetDisk(ctx context.Context, name string) (*Disk, error) {
+	disks, err := c.ListDisks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range disks {
+		if disks[i].Name == name {
+			return &disks[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("disk %q: %w", name, ErrNotFound)
+}
+
+// CreateDisk creates a disk of sizeGiB GiB in the given format.
+func (c *Client) CreateDisk(ctx context.Context, name string, sizeGiB float64, format string) error {
+	args := []string{"disk", "create", name, fmt.Sprintf("--size=%gG", sizeGiB)}
+
+	if format != "" {
+		args = append(args, "--format="+format)
+	}
+
+	args = append(args, "--tty=false")
+
+	return c.mutate(ctx, args...)
+}
+
+// ResizeDisk grows a disk to sizeGiB GiB.
+func (c *Client) ResizeDisk(ctx context.Context, name string, sizeGiB float64) error {
+	return c.mutateIdempotent(ctx, "disk", "resize", name, fmt.Sprintf("--size=%gG", sizeGiB), "--tty=false")
+}
+
+// DeleteDisk deletes a disk.
+func (c *Client) DeleteDisk(ctx context.Context, name string) error {
+	return c.mutateIdempotent(ctx, "disk", "delete", name)
+}
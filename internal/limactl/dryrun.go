@@ -0,0 +1,41 @@
+package limactl
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder collects the command lines that were skipped in dry-run mode.
+type Recorder struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a context whose skipped dry-run commands are collected
+// by the returned Recorder.
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	rec := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, rec), rec
+}
+
+// Commands returns the recorded command lines in the order they were skipped.
+func (r *Recorder) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.commands...)
+}
+
+func (r *Recorder) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.commands = append(r.commands, line)
+}
+
+func recorderFrom(ctx context.Context) *Recorder {
+	rec, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return rec
+}
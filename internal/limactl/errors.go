@@ -0,0 +1,112 @@
+package limactl
+
+import (
+	"errors"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// ErrorClass is a kind of limactl failure with a known remedy.
+type ErrorClass int
+
+const (
+	// ErrorUnknown is any failure that is not recognised.
+	ErrorUnknown ErrorClass = iota
+
+	// ErrorBinaryNotFound means limactl could not be run at all.
+	ErrorBinaryNotFound
+
+	// ErrorInstanceExists means an instance of the same name already exists.
+	ErrorInstanceExists
+
+	// ErrorNotFound means the instance does not exist.
+	ErrorNotFound
+
+	// ErrorDiskNotFound means the disk, or a disk the instance references,
+	// does not exist.
+	ErrorDiskNotFound
+
+	// ErrorDiskInUse means the disk is attached to another instance.
+	ErrorDiskInUse
+
+	// ErrorUnsupportedVMType means the host cannot run the vmType.
+	ErrorUnsupportedVMType
+
+	// ErrorUnsupportedArch means the host cannot run the architecture.
+	ErrorUnsupportedArch
+
+	// ErrorTemplateFetch means the template could not be found or downloaded.
+	ErrorTemplateFetch
+
+	// ErrorNoSpace means the host ran out of disk space.
+	ErrorNoSpace
+
+	// ErrorNetworkPermission means a shared or bridged network could not be
+	// set up because the sudoers file for Lima is missing or outdated.
+	ErrorNetworkPermission
+)
+
+// errorClasses recognise failures by their messages. A class matches when all
+// strings of one of its patterns appear in the same line of the lowercased
+// output. The first matching class wins.
+var errorClasses = []struct {
+	class    ErrorClass
+	patterns [][]string
+}{
+	{ErrorBinaryNotFound, [][]string{{"limactl: command not found"}, {"limactl: not found"}}},
+	{ErrorNetworkPermission, [][]string{{"sudoers"}, {"socket_vmnet", "permission denied"}, {"sudo: a password is required"}}},
+	{ErrorDiskInUse, [][]string{{"in use by instance"}, {"disk", "is already in use"}, {"disk", "is attached to"}}},
+	{ErrorInstanceExists, [][]string{{"instance", "already exists"}}},
+	{ErrorNoSpace, [][]string{{"no space left on device"}, {"not enough disk space"}, {"insufficient disk space"}}},
+	{ErrorUnsupportedVMType, [][]string{{"vmtype", "not supported"}, {"unknown vmtype"}, {"field `vmtype` must be"}, {"vz driver requires"}}},
+	{ErrorUnsupportedArch, [][]string{{"arch", "not supported"}, {"unsupported arch"}, {"field `arch` must be"}, {"qemu binary for the architecture"}}},
+	{ErrorTemplateFetch, [][]string{{"template", "failed to download"}, {"template", "failed to fetch"}, {"template", "failed to locate"}, {"template", "not found"}, {"template", "does not exist"}, {"template", "no such file"}}},
+	{ErrorDiskNotFound, [][]string{{`disk "`, `" does not exist`}}},
+	{ErrorNotFound, [][]string{{`instance "`, `" does not exist`}}},
+}
+
+// Classify returns the class of a limactl failure, or ErrorUnknown.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorUnknown
+	}
+
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return ErrorBinaryNotFound
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return ErrorUnknown
+	}
+
+	output := strings.ToLower(string(cmdErr.Output) + "\n" + strings.Join(cmdErr.Messages, "\n"))
+	lines := strings.Split(output, "\n")
+	for _, c := range errorClasses {
+		for _, pattern := range c.patterns {
+			for _, line := range lines {
+				if containsAll(line, pattern) {
+					return c.class
+				}
+			}
+		}
+	}
+
+	// A remote shell reports a missing command with exit code 127.
+	if ExitCode(err) == 127 {
+		return ErrorBinaryNotFound
+	}
+
+	return ErrorUnknown
+}
+
+func containsAll(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if !strings.Contains(s, substr) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package limactl
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e exitCodeError) ExitCode() int {
+	return int(e)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ErrorUnknown},
+		{"not a command error", errors.New("boom"), ErrorUnknown},
+		{"unrecognised", &CommandError{Output: []byte("something broke")}, ErrorUnknown},
+		{"binary missing locally", &CommandError{Err: &exec.Error{Name: "limactl", Err: exec.ErrNotFound}}, ErrorBinaryNotFound},
+		{"binary missing remotely", &CommandError{Output: []byte("sh: 1: limactl: not found\n"), Err: exitCodeError(127)}, ErrorBinaryNotFound},
+		{"exit code 127", &CommandError{Err: exitCodeError(127)}, ErrorBinaryNotFound},
+		{"instance exists", &CommandError{Messages: []string{`instance "default" already exists ("/home/me/.lima/default")`}}, ErrorInstanceExists},
+		{"instance not found", &CommandError{Messages: []string{`instance "default" does not exist, run ` + "`limactl create default`"}}, ErrorNotFound},
+		{"disk not found", &CommandError{Messages: []string{`disk "data" does not exist`}}, ErrorDiskNotFound},
+		{"template file", &CommandError{Messages: []string{`template "/tmp/missing.yaml" does not exist`}}, ErrorTemplateFetch},
+		{"network not found", &CommandError{Messages: []string{`networks.yaml: network "bridged" does not exist`}}, ErrorUnknown},
+		{"file not found", &CommandError{Output: []byte("/home/me/.lima/_config/override.yaml does not exist\n")}, ErrorUnknown},
+		{"disk in use", &CommandError{Output: []byte(`level=fatal msg="failed to run attach disk \"data\", in use by instance \"other\""`)}, ErrorDiskInUse},
+		{"vmType", &CommandError{Messages: []string{`field ` + "`vmType`" + ` must be "qemu", "vz", "wsl2"; got "hyperv"`}}, ErrorUnsupportedVMType},
+		{"vz on old macOS", &CommandError{Messages: []string{"VZ driver requires macOS 13 or higher to run"}}, ErrorUnsupportedVMType},
+		{"arch", &CommandError{Messages: []string{`failed to find the QEMU binary for the architecture "riscv64"`}}, ErrorUnsupportedArch},
+		{"template", &CommandError{Messages: []string{`failed to locate template "template://nope"`}}, ErrorTemplateFetch},
+		{"template download", &CommandError{Output: []byte("failed to download template https://example.com/t.yaml: 404 Not Found\n")}, ErrorTemplateFetch},
+		{"no space", &CommandError{Output: []byte("write /home/me/.lima/default/diffdisk: no space left on device\n")}, ErrorNoSpace},
+		{"sudoers", &CommandError{Messages: []string{"sudoers file not found: open /etc/sudoers.d/lima: no such file or directory"}}, ErrorNetworkPermission},
+		{"class order", &CommandError{Output: []byte("disk \"data\" is already in use by instance \"a\"\ninstance \"b\" does not exist\n")}, ErrorDiskInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyWrapped(t *testing.T) {
+	err := fmt.Errorf("starting: %w", &CommandError{Messages: []string{"no space left on device"}})
+
+	if got := Classify(err); got != ErrorNoSpace {
+		t.Errorf("Classify() = %d, want %d", got, ErrorNoSpace)
+	}
+}
@@ -0,0 +1,72 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// HostCapacity is the CPU and memory of the machine that runs the instances.
+type HostCapacity struct {
+	CPUs   int
+	Memory int64 // bytes
+}
+
+// hostCapacityScript prints the number of CPUs and the memory in bytes of a
+// macOS or Linux host on two lines.
+const hostCapacityScript = `if [ "$(uname -s)" = Darwin ]; then sysctl -n hw.ncpu hw.memsize; ` +
+	`else getconf _NPROCESSORS_ONLN; echo $(( $(awk '/^MemTotal:/ {print $2}' /proc/meminfo) * 1024 )); fi`
+
+// HostCapacity returns the capacity of the host limactl runs on, which is the
+// remote host when the client uses SSH.
+func (c *Client) HostCapacity(ctx context.Context) (*HostCapacity, error) {
+	if _, ok := c.transport.(LocalTransport); ok {
+		return localHostCapacity()
+	}
+
+	var stdout bytes.Buffer
+	process, err := c.transport.Start(ctx, &Command{
+		Args:   []string{"sh", "-c", hostCapacityScript},
+		Stdout: &stdout,
+	})
+	if err == nil {
+		err = c.wait(ctx, process)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHostCapacity(stdout.String())
+}
+
+// localHostCapacity returns the capacity of the machine the provider runs on.
+func localHostCapacity() (*HostCapacity, error) {
+	memory, err := hostMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HostCapacity{CPUs: runtime.NumCPU(), Memory: memory}, nil
+}
+
+func parseHostCapacity(output string) (*HostCapacity, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected host capacity output %q", output)
+	}
+
+	cpus, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CPU count %q", fields[0])
+	}
+
+	memory, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory size %q", fields[1])
+	}
+
+	return &HostCapacity{CPUs: cpus, Memory: memory}, nil
+}
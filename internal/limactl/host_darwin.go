@@ -0,0 +1,12 @@
+package limactl
+
+import "golang.org/x/sys/unix"
+
+func hostMemory() (int64, error) {
+	memory, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(memory), nil
+}
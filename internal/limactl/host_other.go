@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package limactl
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func hostMemory() (int64, error) {
+	return 0, fmt.Errorf("reading the host memory is not supported on %s", runtime.GOOS)
+}
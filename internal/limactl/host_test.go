@@ -0,0 +1,34 @@
+package limactl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseHostCapacity(t *testing.T) {
+	host, err := parseHostCapacity("10\n34359738368\n")
+	if err != nil {
+		t.Fatalf("parseHostCapacity: %v", err)
+	}
+
+	if host.CPUs != 10 || host.Memory != 32<<30 {
+		t.Errorf("unexpected capacity %+v", host)
+	}
+
+	for _, output := range []string{"", "10\n", "ten\n1024\n", "10\n32G\n"} {
+		if _, err := parseHostCapacity(output); err == nil {
+			t.Errorf("expected an error for %q", output)
+		}
+	}
+}
+
+func TestHostCapacityLocal(t *testing.T) {
+	host, err := New(Config{}).HostCapacity(context.Background())
+	if err != nil {
+		t.Skipf("host memory not available: %v", err)
+	}
+
+	if host.CPUs <= 0 || host.Memory <= 0 {
+		t.Errorf("unexpected capacity %+v", host)
+	}
+}
@@ -0,0 +1,161 @@
+package limactl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Instance status values reported by limactl list.
+const (
+	StatusRunning = "Running"
+	StatusStopped = "Stopped"
+	StatusBroken  = "Broken"
+)
+
+// Instance is one entry of `limactl list --json`.
+type Instance struct {
+	Name            string            `json:"name"`
+	Hostname        string            `json:"hostname"`
+	Status          string            `json:"status"`
+	Dir             string            `json:"dir"`
+	VMType          string            `json:"vmType"`
+	Arch            string            `json:"arch"`
+	CPUType         string            `json:"cpuType"`
+	CPUs            int               `json:"cpus"`
+	Memory          int64             `json:"memory"` // bytes
+	Disk            int64             `json:"disk"`   // bytes
+	Message         string            `json:"message"`
+	AdditionalDisks []AdditionalDisk  `json:"additionalDisks"`
+	Networks        []Network         `json:"network"`
+	SSHLocalPort    int               `json:"sshLocalPort"`
+	SSHConfigFile   string            `json:"sshConfigFile"`
+	SSHAddress      string            `json:"sshAddress"`
+	HostAgentPID    int               `json:"hostAgentPID"`
+	DriverPID       int               `json:"driverPID"`
+	Protected       bool              `json:"protected"`
+	LimaVersion     string            `json:"limaVersion"`
+	Param           map[string]string `json:"param"`
+	HostOS          string            `json:"hostOS"`
+	HostArch        string            `json:"hostArch"`
+	LimaHome        string            `json:"limaHome"`
+	IdentityFile    string            `json:"identityFile"`
+
+	// Config is the effective lima.yaml of the instance.
+	Config *InstanceConfig `json:"config"`
+}
+
+// AdditionalDisk references a lima_disk attached to an instance.
+type AdditionalDisk struct {
+	Name       string   `yaml:"name" json:"name"`
+	Format     *bool    `yaml:"format,omitempty" json:"format,omitempty"`
+	FSType     *string  `yaml:"fsType,omitempty" json:"fsType,omitempty"`
+	FSArgs     []string `yaml:"fsArgs,omitempty" json:"fsArgs,omitempty"`
+	MountPoint string   `yaml:"mountPoint,omitempty" json:"mountPoint,omitempty"`
+}
+
+// Network is a network interface of an instance.
+type Network struct {
+	Lima       string `yaml:"lima,omitempty" json:"lima,omitempty"`
+	Socket     string `yaml:"socket,omitempty" json:"socket,omitempty"`
+	VZNAT      *bool  `yaml:"vzNAT,omitempty" json:"vzNAT,omitempty"`
+	MACAddress string `yaml:"macAddress,omitempty" json:"macAddress,omitempty"`
+	Interface  string `yaml:"interface,omitempty" json:"interface,omitempty"`
+	Metric     *int   `yaml:"metric,omitempty" json:"metric,omitempty"`
+}
+
+// ListInstances returns every instance in LIMA_HOME.
+func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
+	if c.store != nil {
+		instances, err := c.store.ListInstances()
+		if err == nil {
+			return instances, nil
+		}
+
+		tflog.Debug(ctx, "Reading LIMA_HOME failed, falling back to limactl list", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	output, err := c.Run(ctx, "list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	if err := decodeLines(output, &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse instance list JSON: %w", err)
+	}
+
+	return instances, nil
+}
+
+// GetInstance returns the named instance, or ErrNotFound.
+func (c *Client) GetInstance(ctx context.Context, name string) (*Instance, error) {
+	instances, err := c.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range instances {
+		if instances[i].Name == name {
+			return &instances[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("instance %q: %w", name, ErrNotFound)
+}
+
+// CreateInstance runs `limactl create --name=<name> <flags...> [template]`.
+func (c *Client) CreateInstance(ctx context.Context, name string, template string, flags []string) error {
+	args := []string{"create", "--name=" + name}
+	args = append(args, flags...)
+	args = append(args, "--tty=false")
+
+	if template != "" {
+		args = append(args, template)
+	}
+
+	return c.mutate(ctx, args...)
+}
+
+// StartInstance starts a stopped instance. When ctx has a deadline it is
+// passed as `--timeout`, so that limactl waits for the boot as long as the
+// caller does instead of its own default of ten minutes.
+func (c *Client) StartInstance(ctx context.Context, name string) error {
+	// Retries get the time that is left
+	return c.mutateIdempotentFunc(ctx, func() []string {
+		args := []string{"start", name}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline).Round(time.Second)
+			if timeout < time.Second {
+				timeout = time.Second
+			}
+
+			args = append(args, "--timeout="+timeout.String())
+		}
+
+		return args
+	})
+}
+
+// StopInstance stops a running instance.
+func (c *Client) StopInstance(ctx context.Context, name string) error {
+	return c.mutateIdempotent(ctx, "stop", name)
+}
+
+// EditInstance applies flags to a stopped instance with `limactl edit`.
+func (c *Client) EditInstance(ctx context.Context, name string, flags []string) error {
+	args := []string{"edit", name}
+	args = append(args, flags...)
+
+	return c.mutateIdempotent(ctx, args...)
+}
+
+// DeleteInstance deletes an instance.
+func (c *Client) DeleteInstance(ctx context.Context, name string) error {
+	return c.mutateIdempotent(ctx, "delete", name)
+}
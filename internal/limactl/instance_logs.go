@@ -0,0 +1,119 @@
+package limactl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InstanceLogPatterns are the files in an instance directory that explain why
+// it failed to boot.
+var InstanceLogPatterns = []string{"ha.stderr.log", "serial*.log", "cloud-init-output.log"}
+
+// InstanceLog is a log file read from an instance directory.
+type InstanceLog struct {
+	Name    string
+	Content string
+}
+
+// InstanceLogs reads the files matching InstanceLogPatterns from dir, the
+// directory of an instance. With lines greater than zero only the end of each
+// file is returned. Files that do not exist are skipped.
+func (c *Client) InstanceLogs(ctx context.Context, dir string, lines int) ([]InstanceLog, error) {
+	if _, ok := c.transport.(LocalTransport); ok {
+		return localInstanceLogs(dir, lines)
+	}
+
+	return c.remoteInstanceLogs(ctx, dir, lines)
+}
+
+func localInstanceLogs(dir string, lines int) ([]InstanceLog, error) {
+	var logs []InstanceLog
+
+	for _, pattern := range InstanceLogPatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			data, err := os.ReadFile(match)
+			if err != nil {
+				return nil, err
+			}
+
+			logs = append(logs, InstanceLog{Name: filepath.Base(match), Content: LastLines(string(data), lines)})
+		}
+	}
+
+	return logs, nil
+}
+
+// remoteLogHeader separates the files printed by remoteInstanceLogs.
+const remoteLogHeader = "==> lima-provider-log "
+
+func (c *Client) remoteInstanceLogs(ctx context.Context, dir string, lines int) ([]InstanceLog, error) {
+	read := "cat \"$f\""
+	if lines > 0 {
+		read = "tail -n " + strconv.Itoa(lines) + " \"$f\""
+	}
+
+	script := "cd \"$1\" || exit 1; for f in " + strings.Join(InstanceLogPatterns, " ") + "; do " +
+		"[ -f \"$f\" ] || continue; echo \"" + remoteLogHeader + "$f\"; " + read + "; done"
+
+	var stdout bytes.Buffer
+	process, err := c.transport.Start(ctx, &Command{
+		Args:   []string{"sh", "-c", script, "sh", dir},
+		Stdout: &stdout,
+	})
+	if err == nil {
+		err = c.wait(ctx, process)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []InstanceLog
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if name, ok := strings.CutPrefix(line, remoteLogHeader); ok {
+			logs = append(logs, InstanceLog{Name: path.Base(name)})
+			continue
+		}
+
+		if len(logs) > 0 {
+			logs[len(logs)-1].Content += line + "\n"
+		}
+	}
+
+	return logs, scanner.Err()
+}
+
+// LastLines returns the last n lines of s, or all of s when n is not positive.
+func LastLines(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+
+	trimmed := strings.TrimSuffix(s, "\n")
+	for i := len(trimmed) - 1; i >= 0; i-- {
+		if trimmed[i] == '\n' {
+			n--
+			if n == 0 {
+				return s[i+1:]
+			}
+		}
+	}
+
+	return s
+}
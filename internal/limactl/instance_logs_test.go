@@ -0,0 +1,75 @@
+package limactl
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstanceLogs(t *testing.T) {
+	dir := t.TempDir()
+
+	var serial strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&serial, "boot %d\n", i)
+	}
+
+	writeFile(t, filepath.Join(dir, "ha.stderr.log"), []byte(`{"level":"fatal","msg":"did not receive an event"}`+"\n"))
+	writeFile(t, filepath.Join(dir, "serialv.log"), []byte(serial.String()))
+	writeFile(t, filepath.Join(dir, "serial.log"), nil)
+	writeFile(t, filepath.Join(dir, "lima.yaml"), []byte("cpus: 2\n"))
+
+	clients := map[string]*Client{
+		"local": New(Config{}),
+		"ssh":   New(Config{Transport: testSSHTransport(t)}),
+	}
+
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			logs, err := client.InstanceLogs(context.Background(), dir, 3)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			got := map[string]string{}
+			var names []string
+			for _, log := range logs {
+				got[log.Name] = log.Content
+				names = append(names, log.Name)
+			}
+
+			if strings.Join(names, ",") != "ha.stderr.log,serial.log,serialv.log" {
+				t.Errorf("unexpected files: %v", names)
+			}
+
+			if got["serialv.log"] != "boot 98\nboot 99\nboot 100\n" {
+				t.Errorf("unexpected serialv.log tail: %q", got["serialv.log"])
+			}
+
+			if !strings.Contains(got["ha.stderr.log"], "did not receive an event") {
+				t.Errorf("unexpected ha.stderr.log: %q", got["ha.stderr.log"])
+			}
+		})
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"a\nb\n", 0, "a\nb\n"},
+	}
+
+	for _, test := range tests {
+		if got := LastLines(test.in, test.n); got != test.want {
+			t.Errorf("LastLines(%q, %d) = %q, want %q", test.in, test.n, got, test.want)
+		}
+	}
+}
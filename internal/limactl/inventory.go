@@ -0,0 +1,122 @@
+package limactl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultInventoryTTL is how long an inventory snapshot stays valid.
+const DefaultInventoryTTL = 10 * time.Second
+
+// Inventory caches the instance and disk lists for a short time so that a
+// refresh of many resources runs `limactl list --json` and
+// `limactl disk list --json` once instead of once per resource. Every
+// mutating client call invalidates it.
+type Inventory struct {
+	client *Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu          sync.Mutex
+	instances   []Instance
+	instancesAt time.Time
+	disks       []Disk
+	disksAt     time.Time
+}
+
+func newInventory(client *Client, ttl time.Duration) *Inventory {
+	return &Inventory{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Instances returns the cached instance list, listing again when the
+// snapshot is older than the TTL. Concurrent callers share one listing.
+func (inv *Inventory) Instances(ctx context.Context) ([]Instance, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.instances == nil || inv.now().Sub(inv.instancesAt) > inv.ttl {
+		instances, err := inv.client.ListInstances(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		inv.instances = instances
+		if inv.instances == nil {
+			inv.instances = []Instance{}
+		}
+		inv.instancesAt = inv.now()
+	}
+
+	return inv.instances, nil
+}
+
+// Instance returns the named instance from the snapshot, or ErrNotFound.
+func (inv *Inventory) Instance(ctx context.Context, name string) (*Instance, error) {
+	instances, err := inv.Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range instances {
+		if instances[i].Name == name {
+			instance := instances[i]
+			return &instance, nil
+		}
+	}
+
+	return nil, fmt.Errorf("instance %q: %w", name, ErrNotFound)
+}
+
+// Disks returns the cached disk list, listing again when the snapshot is
+// older than the TTL. Concurrent callers share one listing.
+func (inv *Inventory) Disks(ctx context.Context) ([]Disk, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.disks == nil || inv.now().Sub(inv.disksAt) > inv.ttl {
+		disks, err := inv.client.ListDisks(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		inv.disks = disks
+		if inv.disks == nil {
+			inv.disks = []Disk{}
+		}
+		inv.disksAt = inv.now()
+	}
+
+	return inv.disks, nil
+}
+
+// Disk returns the named disk from the snapshot, or ErrNotFound.
+func (inv *Inventory) Disk(ctx context.Context, name string) (*Disk, error) {
+	disks, err := inv.Disks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range disks {
+		if disks[i].Name == name {
+			disk := disks[i]
+			return &disk, nil
+		}
+	}
+
+	return nil, fmt.Errorf("disk %q: %w", name, ErrNotFound)
+}
+
+// Invalidate drops the snapshot so that the next lookup lists again.
+func (inv *Inventory) Invalidate() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.instances = nil
+	inv.disks = nil
+}
@@ -0,0 +1,84 @@
+package limactl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventory(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+
+	path := writeScript(t, `
+echo "$*" >> `+calls+`
+case "$1" in
+list) echo '{"name":"dev","status":"Running"}'; echo '{"name":"ci","status":"Stopped"}' ;;
+disk) echo '{"name":"data","size":10737418240,"instance":"dev"}' ;;
+esac
+`)
+
+	client := New(Config{Path: path})
+	inv := client.Inventory()
+
+	now := time.Now()
+	inv.now = func() time.Time { return now }
+
+	countCalls := func() int {
+		data, err := os.ReadFile(calls)
+		if errors.Is(err, os.ErrNotExist) {
+			return 0
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	ctx := context.Background()
+
+	for _, name := range []string{"dev", "ci"} {
+		if _, err := inv.Instance(ctx, name); err != nil {
+			t.Fatalf("unexpected error for %s: %s", name, err)
+		}
+	}
+
+	if _, err := inv.Instance(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if disk, err := inv.Disk(ctx, "data"); err != nil || disk.Instance != "dev" {
+		t.Errorf("unexpected disk %+v, error %v", disk, err)
+	}
+
+	if got := countCalls(); got != 2 {
+		t.Fatalf("expected one instance and one disk listing, got %d calls", got)
+	}
+
+	// Mutations invalidate the snapshot.
+	if err := client.StopInstance(ctx, "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inv.Instance(ctx, "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := countCalls(); got != 4 {
+		t.Fatalf("expected stop and a new listing, got %d calls", got)
+	}
+
+	// Snapshots expire after the TTL.
+	now = now.Add(DefaultInventoryTTL + time.Second)
+
+	if _, err := inv.Instance(ctx, "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := countCalls(); got != 5 {
+		t.Fatalf("expected a new listing after the TTL, got %d calls", got)
+	}
+}
@@ -0,0 +1,31 @@
+package limactl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// decodeLines decodes line-delimited JSON objects, as printed by the
+// `--json` flag of limactl list commands, into out.
+func decodeLines[T any](data []byte, out *[]T) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			return fmt.Errorf("%w\nLine: %s", err, line)
+		}
+
+		*out = append(*out, v)
+	}
+
+	return scanner.Err()
+}
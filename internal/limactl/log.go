@@ -0,0 +1,107 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// logWriter forwards limactl's stderr to tflog one line at a time. Lines
+// written by `--log-format=json` are logged at their own level with their
+// fields; anything else is logged as debug output.
+type logWriter struct {
+	ctx context.Context
+
+	mu       sync.Mutex
+	partial  []byte
+	messages []string
+}
+
+func newLogWriter(ctx context.Context) *logWriter {
+	return &logWriter{ctx: ctx}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.line(w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Close logs a trailing line that was not terminated by a newline.
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.line(w.partial)
+		w.partial = nil
+	}
+
+	return nil
+}
+
+// Messages returns the messages of error, fatal and panic entries.
+func (w *logWriter) Messages() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.messages
+}
+
+func (w *logWriter) line(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(line, &entry); err != nil {
+		tflog.Debug(w.ctx, "limactl output", map[string]any{"output": string(line)})
+		return
+	}
+
+	level, _ := entry["level"].(string)
+	msg, _ := entry["msg"].(string)
+
+	fields := map[string]any{}
+	for k, v := range entry {
+		if k != "level" && k != "msg" && k != "time" {
+			fields[k] = v
+		}
+	}
+
+	switch level {
+	case "trace":
+		tflog.Trace(w.ctx, msg, fields)
+	case "debug":
+		tflog.Debug(w.ctx, msg, fields)
+	case "info":
+		tflog.Info(w.ctx, msg, fields)
+	case "warning", "warn":
+		tflog.Warn(w.ctx, msg, fields)
+	case "error", "fatal", "panic":
+		tflog.Error(w.ctx, msg, fields)
+
+		if errField, ok := entry["error"].(string); ok && errField != "" {
+			msg += ": " + errField
+		}
+		w.messages = append(w.messages, msg)
+	default:
+		tflog.Debug(w.ctx, "limactl output", map[string]any{"output": string(line)})
+	}
+}
@@ -0,0 +1,96 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestClientJSONLogs(t *testing.T) {
+	path := writeScript(t, `
+case "$*" in
+--version) echo "limactl version 1.0.3"; exit 0 ;;
+esac
+if [ "$1" != "--log-format=json" ]; then
+	echo "missing --log-format=json" >&2
+	exit 1
+fi
+shift
+echo '{"level":"info","msg":"Downloading the image","url":"https://example.com/img","time":"2024-01-01T00:00:00Z"}' >&2
+printf 'plain line\n' >&2
+if [ "$1" = "start" ]; then
+	echo '{"level":"error","msg":"boot failed","time":"2024-01-01T00:00:01Z"}' >&2
+	printf '{"level":"fatal","msg":"exiting","error":"timeout","time":"2024-01-01T00:00:02Z"}' >&2
+	exit 1
+fi
+echo '{"name":"dev","status":"Running"}'
+`)
+
+	var logs bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &logs)
+
+	client := New(Config{Path: path})
+	if _, err := client.DetectVersion(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := client.GetInstance(ctx, "dev"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := client.StartInstance(ctx, "dev")
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected CommandError, got %v", err)
+	}
+
+	if want := []string{"boot failed", "exiting: timeout"}; strings.Join(cmdErr.Messages, "|") != strings.Join(want, "|") {
+		t.Errorf("expected messages %q, got %q", want, cmdErr.Messages)
+	}
+
+	if msg := cmdErr.Error(); !strings.Contains(msg, "exiting: timeout") || strings.Contains(msg, "Downloading") {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, entry := range entries {
+		if entry["@message"] == "Downloading the image" {
+			found = true
+			if entry["@level"] != "info" || entry["url"] != "https://example.com/img" {
+				t.Errorf("unexpected log entry: %v", entry)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("limactl log entry not forwarded: %v", entries)
+	}
+}
+
+func TestClientJSONLogsOldVersion(t *testing.T) {
+	path := writeScript(t, `
+case "$1" in
+--version) echo "limactl version 0.23.2" ;;
+--log-format=json) echo "unknown flag: --log-format" >&2; exit 1 ;;
+*) echo '{"name":"dev","status":"Running"}' ;;
+esac
+`)
+
+	client := New(Config{Path: path})
+	if _, err := client.DetectVersion(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := client.GetInstance(context.Background(), "dev"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
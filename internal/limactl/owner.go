@@ -0,0 +1,120 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OwnerFile is the ownership marker in an instance or disk directory.
+const OwnerFile = "terraform-owner.json"
+
+// Owner records which Terraform workspace created an instance or disk.
+// Providers cannot see resource addresses, so Address is made of the resource
+// type and the configured name, such as lima_instance.dev, instead of the
+// label of the resource block.
+type Owner struct {
+	Workspace string    `json:"workspace"`
+	Address   string    `json:"address"`
+	Resource  string    `json:"resource"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Template and MountNone are the create-time settings of an instance
+	// that its configuration does not show, for importing it.
+	Template  string `json:"template,omitempty"`
+	MountNone bool   `json:"mountNone,omitempty"`
+}
+
+// Remote scripts to read and write the marker, with its path as $1. The read
+// script prints nothing when there is no marker.
+const (
+	readOwnerScript  = `[ -f "$1" ] || exit 0; cat "$1"`
+	writeOwnerScript = `cat > "$1"`
+)
+
+// ReadOwner returns the ownership marker in dir, the directory of an instance
+// or disk, or nil when it has none.
+func (c *Client) ReadOwner(ctx context.Context, dir string) (*Owner, error) {
+	var data []byte
+
+	if _, ok := c.transport.(LocalTransport); ok {
+		var err error
+		data, err = os.ReadFile(filepath.Join(dir, OwnerFile))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var stdout bytes.Buffer
+		process, err := c.transport.Start(ctx, &Command{
+			Args:   []string{"sh", "-c", readOwnerScript, "sh", path.Join(dir, OwnerFile)},
+			Stdout: &stdout,
+		})
+		if err == nil {
+			err = c.wait(ctx, process)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data = stdout.Bytes()
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var owner Owner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, fmt.Errorf("failed to parse %s in %s: %w", OwnerFile, dir, err)
+	}
+
+	return &owner, nil
+}
+
+// WriteOwner writes the ownership marker into dir. Nothing is written in
+// dry-run mode.
+func (c *Client) WriteOwner(ctx context.Context, dir string, owner Owner) error {
+	if c.dryRun {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(owner, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if _, ok := c.transport.(LocalTransport); ok {
+		return os.WriteFile(filepath.Join(dir, OwnerFile), data, 0o644)
+	}
+
+	var stderr bytes.Buffer
+	process, err := c.transport.Start(ctx, &Command{
+		Args:   []string{"sh", "-c", writeOwnerScript, "sh", path.Join(dir, OwnerFile)},
+		Stdin:  bytes.NewReader(data),
+		Stderr: &stderr,
+	})
+	if err == nil {
+		err = c.wait(ctx, process)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package limactl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOwnerLocal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := New(Config{})
+
+	owner, err := c.ReadOwner(ctx, dir)
+	if err != nil || owner != nil {
+		t.Fatalf("expected no owner, got %+v, %v", owner, err)
+	}
+
+	want := Owner{Workspace: "infra/default", Resource: "lima_instance", Name: "dev", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if err := c.WriteOwner(ctx, dir, want); err != nil {
+		t.Fatalf("WriteOwner: %v", err)
+	}
+
+	owner, err = c.ReadOwner(ctx, dir)
+	if err != nil {
+		t.Fatalf("ReadOwner: %v", err)
+	}
+	if owner == nil || *owner != want {
+		t.Errorf("got owner %+v, want %+v", owner, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, OwnerFile), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadOwner(ctx, dir); err == nil {
+		t.Error("expected an error for a corrupt marker")
+	}
+}
+
+func TestWriteOwnerDryRun(t *testing.T) {
+	dir := t.TempDir()
+	c := New(Config{DryRun: true})
+
+	if err := c.WriteOwner(context.Background(), dir, Owner{Workspace: "default"}); err != nil {
+		t.Fatalf("WriteOwner: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, OwnerFile)); !os.IsNotExist(err) {
+		t.Errorf("expected no marker in dry-run mode, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package limactl
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// DefaultRetryPolicy retries transient failures for up to about a minute.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  5,
+	InitialDelay: 2 * time.Second,
+	MaxDelay:     30 * time.Second,
+}
+
+// RetryPolicy controls how idempotent commands are retried after a transient
+// failure.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts. 1 disables retries.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the second attempt. It doubles with
+	// every further attempt up to MaxDelay.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// retryableMessages are limactl failures caused by another limactl process
+// holding a lock. They go away once that process is done.
+var retryableMessages = []string{
+	"is locked",
+	"in use by another process",
+	"resource temporarily unavailable",
+}
+
+// Retryable reports whether err is a transient limactl failure that is likely
+// to succeed when the command is run again.
+func Retryable(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	output := strings.ToLower(string(cmdErr.Output) + "\n" + strings.Join(cmdErr.Messages, "\n"))
+	for _, msg := range retryableMessages {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns the wait before the given attempt, counting from 1, with
+// jitter so that parallel operations do not retry in lockstep.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.InitialDelay
+	for i := 2; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+// mutateIdempotent is mutate for commands that can safely run again after a
+// failure, which are retried according to the retry policy. Commands such as
+// `limactl create` must use mutate.
+func (c *Client) mutateIdempotent(ctx context.Context, args ...string) error {
+	return c.mutateIdempotentFunc(ctx, func() []string { return args })
+}
+
+// mutateIdempotentFunc is mutateIdempotent for commands whose arguments
+// depend on when they run, such as a timeout derived from the deadline of
+// ctx. argsFunc is called for every attempt.
+func (c *Client) mutateIdempotentFunc(ctx context.Context, argsFunc func() []string) error {
+	for attempt := 1; ; attempt++ {
+		args := argsFunc()
+		err := c.mutate(ctx, args...)
+		if err == nil || attempt >= c.retry.MaxAttempts || !Retryable(err) {
+			return err
+		}
+
+		delay := c.retry.delay(attempt + 1)
+
+		tflog.Warn(ctx, "Retrying limactl after transient failure", map[string]any{
+			"command": "limactl " + strings.Join(args, " "),
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package limactl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientRetry(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+
+	// Fails with a lock error on the first two attempts.
+	path := writeScript(t, `
+echo x >> `+counter+`
+if [ "$(wc -l < `+counter+`)" -le 2 ]; then
+	echo 'instance "dev" is locked' >&2
+	exit 1
+fi
+`)
+
+	client := New(Config{
+		Path:  path,
+		Retry: &RetryPolicy{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	})
+
+	if err := client.StopInstance(context.Background(), "dev"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n := attempts(t, counter); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestClientRetryStartTimeout(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "args")
+
+	// Fails with a lock error on the first attempt.
+	path := writeScript(t, `
+echo "$@" >> `+log+`
+if [ "$(wc -l < `+log+`)" -le 1 ]; then
+	echo 'instance "dev" is locked' >&2
+	exit 1
+fi
+`)
+
+	client := New(Config{
+		Path:  path,
+		Retry: &RetryPolicy{MaxAttempts: 2, InitialDelay: 2 * time.Second, MaxDelay: 2 * time.Second},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second+400*time.Millisecond)
+	defer cancel()
+
+	if err := client.StartInstance(ctx, "dev"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || lines[0] != "start dev --timeout=30s" || lines[1] == lines[0] {
+		t.Errorf("expected the retry to get the time that is left, got %q", lines)
+	}
+}
+
+func TestClientRetryLimits(t *testing.T) {
+	tests := map[string]struct {
+		output   string
+		run      func(*Client) error
+		attempts int
+	}{
+		"gives up": {
+			output:   "disk is in use by another process",
+			run:      func(c *Client) error { return c.DeleteDisk(context.Background(), "data") },
+			attempts: 3,
+		},
+		"fatal error": {
+			output:   "instance does not exist",
+			run:      func(c *Client) error { return c.StopInstance(context.Background(), "dev") },
+			attempts: 1,
+		},
+		"create is not retried": {
+			output:   "instance is locked",
+			run:      func(c *Client) error { return c.CreateInstance(context.Background(), "dev", "", nil) },
+			attempts: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			counter := filepath.Join(t.TempDir(), "attempts")
+			path := writeScript(t, `
+echo x >> `+counter+`
+echo '`+test.output+`' >&2
+exit 1
+`)
+
+			client := New(Config{
+				Path:  path,
+				Retry: &RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+			})
+
+			if err := test.run(client); err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if n := attempts(t, counter); n != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, n)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	for attempt, upper := range map[int]time.Duration{2: time.Second, 3: 2 * time.Second, 4: 4 * time.Second, 10: 5 * time.Second} {
+		if d := policy.delay(attempt); d < upper/2 || d > upper {
+			t.Errorf("attempt %d: expected delay between %s and %s, got %s", attempt, upper/2, upper, d)
+		}
+	}
+}
+
+func attempts(t *testing.T, counter string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Count(string(data), "x")
+}
@@ -0,0 +1,338 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes the remote host of an SSHTransport.
+type SSHConfig struct {
+	Host string
+	Port int
+	User string
+
+	// PrivateKey is a PEM encoded private key. When empty the keys of the
+	// agent at SSH_AUTH_SOCK are used.
+	PrivateKey []byte
+
+	// KnownHostsPath is the known_hosts file used to verify the host key.
+	KnownHostsPath string
+}
+
+// sshKillTimeout bounds signalling a remote command through a new session.
+const sshKillTimeout = 10 * time.Second
+
+// SSHTransport runs commands on a remote host over SSH. One connection is
+// opened on first use and shared by all commands; it is dialed again once it
+// drops.
+type SSHTransport struct {
+	config SSHConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewSSHTransport returns a transport for the remote host.
+func NewSSHTransport(config SSHConfig) *SSHTransport {
+	if config.Port == 0 {
+		config.Port = 22
+	}
+
+	return &SSHTransport{config: config}
+}
+
+// Address returns the host:port the transport connects to.
+func (t *SSHTransport) Address() string {
+	return net.JoinHostPort(t.config.Host, strconv.Itoa(t.config.Port))
+}
+
+func (t *SSHTransport) Start(ctx context.Context, cmd *Command) (Process, error) {
+	session, err := t.newSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &pidWriter{w: cmd.Stdout}
+	session.Stdin = cmd.Stdin
+	session.Stdout = stdout
+	session.Stderr = cmd.Stderr
+
+	// The remote shell reports its PID before it becomes the command, so
+	// that the command can be signalled when the server ignores signal
+	// requests
+	if err := session.Start("echo $$; exec " + remoteCommandLine(cmd)); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	return &sshProcess{transport: t, session: session, stdout: stdout}, nil
+}
+
+// newSession opens a session on the shared connection. When that fails, the
+// connection is assumed to be broken and dialed again once.
+func (t *SSHTransport) newSession(ctx context.Context) (*ssh.Session, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+
+	t.disconnect(client)
+
+	client, err = t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session to %s: %w", t.Address(), err)
+	}
+
+	return session, nil
+}
+
+func (t *SSHTransport) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	hostKeyCallback, err := knownhosts.New(t.config.KnownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts %s: %w", t.config.KnownHostsPath, err)
+	}
+
+	auth, closeAuth, err := t.authMethod()
+	if err != nil {
+		return nil, err
+	}
+	// The agent is only needed for the handshake
+	defer closeAuth()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", t.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", t.Address(), err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.Address(), &ssh.ClientConfig{
+		User:            t.config.User,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", t.Address(), err)
+	}
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+	t.client = client
+
+	// A dropped connection is forgotten, so that the next command dials
+	// again
+	go func() {
+		_ = client.Wait()
+		t.disconnect(client)
+	}()
+
+	return client, nil
+}
+
+// disconnect closes client and forgets it if it is the shared connection.
+func (t *SSHTransport) disconnect(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == client {
+		t.client = nil
+	}
+	_ = client.Close()
+}
+
+// kill sends sig to the remote process pid with kill(1).
+func (t *SSHTransport) kill(pid int, sig ssh.Signal) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sshKillTimeout)
+	defer cancel()
+
+	session, err := t.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.Run(fmt.Sprintf("kill -%s %d", sig, pid))
+}
+
+// authMethod returns the SSH authentication method and a function that
+// releases what it holds once the handshake is done.
+func (t *SSHTransport) authMethod() (ssh.AuthMethod, func(), error) {
+	if len(t.config.PrivateKey) > 0 {
+		signer, err := ssh.ParsePrivateKey(t.config.PrivateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+		}
+
+		return ssh.PublicKeys(signer), func() {}, nil
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, errors.New("no SSH private key configured and SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+	}
+
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), func() { conn.Close() }, nil
+}
+
+// pidWriter takes the first line written to it as the PID of the remote
+// command and passes the rest on to w.
+type pidWriter struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	line   []byte
+	done   bool
+	remote int
+}
+
+func (p *pidWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	n := len(data)
+	if !p.done {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			p.line = append(p.line, data...)
+			p.mu.Unlock()
+			return n, nil
+		}
+
+		p.line = append(p.line, data[:i]...)
+		p.remote, _ = strconv.Atoi(strings.TrimSpace(string(p.line)))
+		p.done = true
+		data = data[i+1:]
+	}
+	p.mu.Unlock()
+
+	if p.w == nil || len(data) == 0 {
+		return n, nil
+	}
+
+	if _, err := p.w.Write(data); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// pid returns the PID of the remote command, or 0 before it is known.
+func (p *pidWriter) pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.remote
+}
+
+type sshProcess struct {
+	transport *SSHTransport
+	session   *ssh.Session
+	stdout    *pidWriter
+}
+
+func (p *sshProcess) Wait() error {
+	defer p.session.Close()
+
+	err := p.session.Wait()
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &remoteExitError{status: exitErr.ExitStatus(), err: exitErr}
+	}
+
+	return err
+}
+
+func (p *sshProcess) Signal(sig os.Signal) error {
+	var signal ssh.Signal
+	switch sig {
+	case os.Interrupt:
+		signal = ssh.SIGINT
+	case syscall.SIGTERM:
+		signal = ssh.SIGTERM
+	case os.Kill:
+		signal = ssh.SIGKILL
+	default:
+		return fmt.Errorf("unsupported signal %s", sig)
+	}
+
+	// Servers are free to ignore signal requests, so the command is
+	// signalled with kill(1) too once its PID is known
+	err := p.session.Signal(signal)
+	if pid := p.stdout.pid(); pid > 0 {
+		err = p.transport.kill(pid, signal)
+	}
+
+	if sig == os.Kill {
+		// Closing the session makes sure Wait returns
+		_ = p.session.Close()
+		return err
+	}
+
+	return err
+}
+
+// remoteExitError reports the exit status of a remote command in the same
+// way as *exec.ExitError.
+type remoteExitError struct {
+	status int
+	err    error
+}
+
+func (e *remoteExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.status)
+}
+
+func (e *remoteExitError) Unwrap() error {
+	return e.err
+}
+
+func (e *remoteExitError) ExitCode() int {
+	return e.status
+}
+
+// remoteCommandLine renders cmd as a POSIX shell command line. Environment
+// variables are passed through env(1) because most sshd configurations
+// reject environment requests.
+func remoteCommandLine(cmd *Command) string {
+	line := CommandLine(cmd.Args)
+
+	if len(cmd.Env) > 0 {
+		line = "env " + envAssignments(cmd.Env) + " " + line
+	}
+
+	return line
+}
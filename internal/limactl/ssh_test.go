@@ -0,0 +1,312 @@
+package limactl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSSHServer runs a minimal sshd stand-in that executes "exec" requests
+// with sh -c. It returns the port and a known_hosts file for it.
+func startSSHServer(t *testing.T, authorized ssh.PublicKey) (int, string) {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "lima" && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("unexpected listener address %v", listener.Addr())
+	}
+	port := addr.Port
+
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize("127.0.0.1:" + strconv.Itoa(port))}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHostsPath, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return port, knownHostsPath
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			defer channel.Close()
+
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+
+				length := binary.BigEndian.Uint32(req.Payload)
+				command := string(req.Payload[4 : 4+length])
+				_ = req.Reply(true, nil)
+
+				cmd := exec.Command("sh", "-c", command)
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+
+				status := 0
+				if err := cmd.Run(); err != nil {
+					status = 255
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						status = exitErr.ExitCode()
+					}
+				}
+
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+				return
+			}
+		}()
+	}
+}
+
+func TestSSHTransport(t *testing.T) {
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, knownHostsPath := startSSHServer(t, clientSigner.PublicKey())
+
+	transport := NewSSHTransport(SSHConfig{
+		Host:           "127.0.0.1",
+		Port:           port,
+		User:           "lima",
+		PrivateKey:     pem.EncodeToMemory(block),
+		KnownHostsPath: knownHostsPath,
+	})
+
+	client := New(Config{
+		Path:      writeScript(t, `echo "{\"name\":\"$LIMA_HOME\",\"dir\":\"$1 it's\"}"`),
+		LimaHome:  "/srv/lima",
+		Transport: transport,
+	})
+
+	instances, err := client.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(instances) != 1 || instances[0].Name != "/srv/lima" || instances[0].Dir != "list it's" {
+		t.Errorf("unexpected instances: %+v", instances)
+	}
+
+	failing := New(Config{
+		Path:      writeScript(t, `echo "boom" >&2; exit 3`),
+		Transport: transport,
+	})
+
+	_, err = failing.Run(context.Background(), "start", "dev")
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !bytes.Contains(cmdErr.Output, []byte("boom")) {
+		t.Fatalf("expected command error with output, got %v", err)
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("expected exit code 3, got %v", err)
+	}
+}
+
+func TestSSHTransportRejectsUnknownHostKey(t *testing.T) {
+	_, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+	block, _ := ssh.MarshalPrivateKey(clientPriv, "")
+
+	port, _ := startSSHServer(t, clientSigner.PublicKey())
+
+	emptyKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(emptyKnownHosts, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := New(Config{
+		Transport: NewSSHTransport(SSHConfig{
+			Host:           "127.0.0.1",
+			Port:           port,
+			User:           "lima",
+			PrivateKey:     pem.EncodeToMemory(block),
+			KnownHostsPath: emptyKnownHosts,
+		}),
+	})
+
+	if _, err := client.Run(context.Background(), "--version"); err == nil {
+		t.Fatal("expected host key verification to fail")
+	}
+}
+
+// testSSHTransport returns a transport connected to a new startSSHServer.
+func testSSHTransport(t *testing.T) *SSHTransport {
+	t.Helper()
+
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, knownHostsPath := startSSHServer(t, clientSigner.PublicKey())
+
+	return NewSSHTransport(SSHConfig{
+		Host:           "127.0.0.1",
+		Port:           port,
+		User:           "lima",
+		PrivateKey:     pem.EncodeToMemory(block),
+		KnownHostsPath: knownHostsPath,
+	})
+}
+
+func TestSSHTransportReconnects(t *testing.T) {
+	transport := testSSHTransport(t)
+	client := New(Config{Path: writeScript(t, `echo ok`), Transport: transport})
+
+	if _, err := client.Run(context.Background(), "--version"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Drop the connection under the transport
+	transport.mu.Lock()
+	_ = transport.client.Close()
+	transport.mu.Unlock()
+
+	output, err := client.Run(context.Background(), "--version")
+	if err != nil || string(bytes.TrimSpace(output)) != "ok" {
+		t.Fatalf("expected the transport to reconnect, got %q and %v", output, err)
+	}
+}
+
+func TestSSHTransportKill(t *testing.T) {
+	transport := testSSHTransport(t)
+
+	var stdout bytes.Buffer
+	process, err := transport.Start(context.Background(), &Command{
+		Args:   []string{"sh", "-c", "echo started; exec sleep 30"},
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshProcess, ok := process.(*sshProcess)
+	if !ok {
+		t.Fatalf("unexpected process %T", process)
+	}
+
+	var pid int
+	for deadline := time.Now().Add(5 * time.Second); pid == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		pid = sshProcess.stdout.pid()
+	}
+	if pid == 0 {
+		t.Fatal("expected the remote PID to be reported")
+	}
+
+	// The test server ignores signal requests, like many sshd configurations
+	if err := process.Signal(os.Kill); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_ = process.Wait()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the remote process %d to be killed", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "started") {
+		t.Errorf("expected the PID line to be removed from the output, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,508 @@
+package limactl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/go-version"
+)
+
+// ErrUnsupportedLayout is returned by Store when LIMA_HOME was written by a
+// Lima release whose on-disk layout the store reader does not know.
+var ErrUnsupportedLayout = errors.New("unsupported LIMA_HOME layout")
+
+// storeLayoutMaxVersion is the first Lima release whose layout the store
+// reader does not understand. Lima 2.0 moved several lima.yaml fields.
+var storeLayoutMaxVersion = version.Must(version.NewVersion("2.0.0"))
+
+// Limits of the defaults Lima derives from the host when neither lima.yaml nor
+// default.yaml set the CPUs or memory; the disk default is fixed.
+const (
+	defaultMaxCPUs   = 4
+	defaultMaxMemory = 4 << 30
+	defaultDisk      = "100GiB"
+)
+
+// ninePDefaultVersion is the first Lima release that mounts with 9p instead
+// of reverse-sshfs by default on QEMU.
+var ninePDefaultVersion = version.Must(version.NewVersion("1.0.0"))
+
+// Store reads instances and disks directly from a LIMA_HOME directory,
+// following the layout of Lima's pkg/store. It never changes anything;
+// mutations always go through limactl.
+type Store struct {
+	home string
+
+	// hostCapacity returns the capacity of the host the defaults are
+	// derived from, like Lima does each time it loads an instance.
+	hostCapacity func() (*HostCapacity, error)
+}
+
+// NewStore returns a store reader for the LIMA_HOME directory.
+func NewStore(home string) *Store {
+	return &Store{home: home, hostCapacity: localHostCapacity}
+}
+
+// ListInstances returns every instance in the store. It returns
+// ErrUnsupportedLayout if any instance directory has an unknown layout.
+func (s *Store) ListInstances() ([]Instance, error) {
+	names, err := s.entries(s.home)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := s.readConfigFile(filepath.Join(s.home, "_config", "default.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := s.readConfigFile(filepath.Join(s.home, "_config", "override.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	// Without the host capacity the defaults are unknown, limactl resolves
+	// them instead
+	host, err := s.hostCapacity()
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	for _, name := range names {
+		instance, err := s.instance(name, host, defaults, override)
+		if err != nil {
+			return nil, err
+		}
+
+		instances = append(instances, *instance)
+	}
+
+	return instances, nil
+}
+
+// ListDisks returns every disk in the store.
+func (s *Store) ListDisks() ([]Disk, error) {
+	disksDir := filepath.Join(s.home, "_disks")
+
+	names, err := s.entries(disksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []Disk
+	for _, name := range names {
+		disk, err := s.disk(disksDir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		disks = append(disks, *disk)
+	}
+
+	return disks, nil
+}
+
+// entries lists the subdirectories of dir that Lima treats as objects, which
+// excludes names starting with "_" or ".".
+func (s *Store) entries(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), "_") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
+func (s *Store) readConfigFile(path string) (*InstanceConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &InstanceConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data)
+}
+
+func (s *Store) instance(name string, host *HostCapacity, defaults *InstanceConfig, override *InstanceConfig) (*Instance, error) {
+	dir := filepath.Join(s.home, name)
+
+	limaVersion, err := readTrimmed(filepath.Join(dir, "lima-version"))
+	if err != nil {
+		return nil, fmt.Errorf("instance %q: %w: %s", name, ErrUnsupportedLayout, err)
+	}
+
+	v, err := ParseVersion(limaVersion)
+	if err != nil || v.LessThan(MinimumVersion) || v.GreaterThanOrEqual(storeLayoutMaxVersion) {
+		return nil, fmt.Errorf("instance %q: %w: created by Lima %q", name, ErrUnsupportedLayout, limaVersion)
+	}
+
+	instance := &Instance{
+		Name:          name,
+		Hostname:      "lima-" + name,
+		Dir:           dir,
+		LimaVersion:   limaVersion,
+		LimaHome:      s.home,
+		HostOS:        runtime.GOOS,
+		HostArch:      limaArch(runtime.GOARCH),
+		SSHConfigFile: filepath.Join(dir, "ssh.config"),
+		Protected:     fileExists(filepath.Join(dir, "protected")),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "lima.yaml"))
+	if err != nil {
+		instance.Status = StatusBroken
+		instance.Message = err.Error()
+		return instance, nil
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		instance.Status = StatusBroken
+		instance.Message = err.Error()
+		return instance, nil
+	}
+
+	cfg = mergeConfig(defaults, cfg, override)
+	fillDefaults(cfg, dir, v)
+	instance.Config = cfg
+
+	instance.VMType = *cfg.VMType
+	instance.Arch = defaultString(cfg.Arch, instance.HostArch)
+	instance.CPUs = min(defaultMaxCPUs, host.CPUs)
+	if cfg.CPUs != nil {
+		instance.CPUs = *cfg.CPUs
+	}
+
+	instance.Memory = min(defaultMaxMemory, host.Memory/2)
+	if cfg.Memory != nil && *cfg.Memory != "" {
+		if instance.Memory, err = ParseSize(*cfg.Memory); err != nil {
+			return nil, fmt.Errorf("instance %q: %w", name, err)
+		}
+	}
+
+	if instance.Disk, err = ParseSize(defaultString(cfg.Disk, defaultDisk)); err != nil {
+		return nil, fmt.Errorf("instance %q: %w", name, err)
+	}
+
+	instance.AdditionalDisks = cfg.AdditionalDisks
+	instance.Networks = cfg.Networks
+	if cfg.SSH.LocalPort != nil {
+		instance.SSHLocalPort = *cfg.SSH.LocalPort
+	}
+
+	instance.HostAgentPID = readPID(filepath.Join(dir, "ha.pid"))
+	instance.DriverPID = readPID(filepath.Join(dir, instance.VMType+".pid"))
+
+	instance.Status = StatusStopped
+	if instance.HostAgentPID != 0 {
+		instance.Status = StatusRunning
+	}
+
+	return instance, nil
+}
+
+func (s *Store) disk(disksDir string, name string) (*Disk, error) {
+	dir := filepath.Join(disksDir, name)
+
+	disk := &Disk{
+		Name:       name,
+		Dir:        dir,
+		MountPoint: "/mnt/lima-" + name,
+	}
+
+	size, format, err := inspectDisk(filepath.Join(dir, "datadisk"))
+	if err != nil {
+		return nil, fmt.Errorf("disk %q: %w", name, err)
+	}
+
+	disk.Size = size
+	disk.Format = format
+
+	if target, err := os.Readlink(filepath.Join(dir, "in_use_by")); err == nil {
+		disk.Instance = filepath.Base(target)
+		disk.InstanceDir = target
+	}
+
+	return disk, nil
+}
+
+// inspectDisk returns the virtual size and format of a disk image. qcow2
+// images carry their virtual size in the header; anything else is raw.
+func inspectDisk(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(f, header); err == nil && string(header[:4]) == "QFI\xfb" {
+		return int64(binary.BigEndian.Uint64(header[24:32])), "qcow2", nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return info.Size(), "raw", nil
+}
+
+// mergeConfig applies Lima's precedence for the fields the provider reads:
+// override.yaml, then lima.yaml, then default.yaml for scalars and dns, which
+// is taken whole from the first file that sets it. Mounts and networks are
+// combined as described at mergeMounts and mergeNetworks.
+func mergeConfig(defaults *InstanceConfig, cfg *InstanceConfig, override *InstanceConfig) *InstanceConfig {
+	merged := *cfg
+
+	merged.VMType = firstNonNil(override.VMType, cfg.VMType, defaults.VMType)
+	merged.Arch = firstNonNil(override.Arch, cfg.Arch, defaults.Arch)
+	merged.CPUs = firstNonNil(override.CPUs, cfg.CPUs, defaults.CPUs)
+	merged.Memory = firstNonNil(override.Memory, cfg.Memory, defaults.Memory)
+	merged.Disk = firstNonNil(override.Disk, cfg.Disk, defaults.Disk)
+	merged.MountType = firstNonNil(override.MountType, cfg.MountType, defaults.MountType)
+	merged.MountInotify = firstNonNil(override.MountInotify, cfg.MountInotify, defaults.MountInotify)
+	merged.Containerd.System = firstNonNil(override.Containerd.System, cfg.Containerd.System, defaults.Containerd.System)
+	merged.Containerd.User = firstNonNil(override.Containerd.User, cfg.Containerd.User, defaults.Containerd.User)
+	merged.Video.Display = firstNonNil(override.Video.Display, cfg.Video.Display, defaults.Video.Display)
+	merged.Rosetta.Enabled = firstNonNil(override.Rosetta.Enabled, cfg.Rosetta.Enabled, defaults.Rosetta.Enabled)
+	merged.Rosetta.BinFmt = firstNonNil(override.Rosetta.BinFmt, cfg.Rosetta.BinFmt, defaults.Rosetta.BinFmt)
+	merged.Plain = firstNonNil(override.Plain, cfg.Plain, defaults.Plain)
+
+	merged.DNS = firstNonEmpty(override.DNS, cfg.DNS, defaults.DNS)
+	merged.Mounts = mergeMounts(slices.Concat(defaults.Mounts, cfg.Mounts, override.Mounts))
+	merged.Networks = mergeNetworks(slices.Concat(defaults.Networks, cfg.Networks, override.Networks))
+
+	return &merged
+}
+
+// fillDefaults sets the fields the provider reads that neither lima.yaml,
+// default.yaml nor override.yaml set to the defaults of the Lima release that
+// created the instance in dir, as limactl list reports them. Like Lima, an
+// existing instance runs on vz if it has a vz-identifier and on qemu
+// otherwise, whatever the host.
+func fillDefaults(cfg *InstanceConfig, dir string, limaVersion *version.Version) {
+	if defaultString(cfg.VMType, "default") == "default" {
+		vmType := "qemu"
+		if fileExists(filepath.Join(dir, "vz-identifier")) {
+			vmType = "vz"
+		}
+		cfg.VMType = &vmType
+	}
+
+	if defaultString(cfg.MountType, "default") == "default" {
+		mountType := "reverse-sshfs"
+		switch {
+		case *cfg.VMType == "vz":
+			mountType = "virtiofs"
+		case *cfg.VMType == "qemu" && !limaVersion.LessThan(ninePDefaultVersion):
+			mountType = "9p"
+		}
+		cfg.MountType = &mountType
+	}
+
+	no, yes := false, true
+	if cfg.Plain == nil {
+		cfg.Plain = &no
+	}
+	if cfg.MountInotify == nil {
+		cfg.MountInotify = &no
+	}
+	if cfg.Video.Display == nil {
+		display := "none"
+		cfg.Video.Display = &display
+	}
+	if cfg.Rosetta.Enabled == nil {
+		cfg.Rosetta.Enabled = &no
+	}
+	if cfg.Rosetta.BinFmt == nil {
+		cfg.Rosetta.BinFmt = &no
+	}
+	if cfg.Containerd.System == nil {
+		cfg.Containerd.System = &no
+	}
+	if cfg.Containerd.User == nil {
+		cfg.Containerd.User = &yes
+	}
+
+	// Plain instances have no mounts and no containerd
+	if *cfg.Plain {
+		cfg.Mounts = nil
+		cfg.Containerd = Containerd{System: &no, User: &no}
+	}
+}
+
+// mergeMounts combines the mounts of default.yaml, lima.yaml and
+// override.yaml, in that order. A later mount with the same location does not
+// add an entry but overrides the fields it sets.
+func mergeMounts(mounts []Mount) []Mount {
+	merged := make([]Mount, 0, len(mounts))
+	locations := map[string]int{}
+
+	for _, mount := range mounts {
+		mount.Location = filepath.Clean(mount.Location)
+
+		i, ok := locations[mount.Location]
+		if !ok {
+			locations[mount.Location] = len(merged)
+			merged = append(merged, mount)
+			continue
+		}
+
+		if mount.Writable != nil {
+			merged[i].Writable = mount.Writable
+		}
+		if mount.MountPoint != nil {
+			merged[i].MountPoint = mount.MountPoint
+		}
+	}
+
+	return merged
+}
+
+// mergeNetworks combines the networks of default.yaml, lima.yaml and
+// override.yaml, in that order. A later network with the same interface name
+// replaces the kind of network and overrides the other fields it sets;
+// networks without an interface name are always added.
+func mergeNetworks(networks []Network) []Network {
+	merged := make([]Network, 0, len(networks))
+	interfaces := map[string]int{}
+
+	for _, network := range networks {
+		i, ok := interfaces[network.Interface]
+		if !ok {
+			if network.Interface != "" {
+				interfaces[network.Interface] = len(merged)
+			}
+			merged = append(merged, network)
+			continue
+		}
+
+		switch {
+		case network.Lima != "":
+			merged[i].Lima, merged[i].Socket, merged[i].VZNAT = network.Lima, "", nil
+		case network.Socket != "":
+			merged[i].Lima, merged[i].Socket, merged[i].VZNAT = "", network.Socket, nil
+		case network.VZNAT != nil && *network.VZNAT:
+			merged[i].Lima, merged[i].Socket, merged[i].VZNAT = "", "", network.VZNAT
+		}
+		if network.MACAddress != "" {
+			merged[i].MACAddress = network.MACAddress
+		}
+		if network.Metric != nil {
+			merged[i].Metric = network.Metric
+		}
+	}
+
+	return merged
+}
+
+func firstNonNil[T any](values ...*T) *T {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func firstNonEmpty[T any](values ...[]T) []T {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func defaultString(s *string, def string) string {
+	if s == nil || *s == "" {
+		return def
+	}
+
+	return *s
+}
+
+// readPID returns the PID stored in path if that process is still alive.
+func readPID(path string) int {
+	data, err := readTrimmed(path)
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(data)
+	if err != nil || pid <= 0 {
+		return 0
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0
+	}
+
+	// Signal 0 only checks for existence. EPERM means the process exists
+	// but belongs to another user.
+	if err := process.Signal(syscall.Signal(0)); err != nil && !errors.Is(err, os.ErrPermission) {
+		return 0
+	}
+
+	return pid
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// limaArch converts a GOARCH to Lima's architecture names.
+func limaArch(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	case "arm":
+		return "armv7l"
+	default:
+		return goarch
+	}
+}
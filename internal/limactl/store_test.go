@@ -0,0 +1,319 @@
+package limactl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	home := t.TempDir()
+
+	writeFile(t, filepath.Join(home, "_config", "override.yaml"), []byte("cpus: 6\n"))
+
+	writeFile(t, filepath.Join(home, "dev", "lima-version"), []byte("1.0.3\n"))
+	writeFile(t, filepath.Join(home, "dev", "lima.yaml"), []byte(`
+vmType: vz
+cpus: 2
+memory: 8GiB
+mounts:
+- location: "~"
+  writable: true
+additionalDisks:
+- data
+dns: [1.1.1.1]
+`))
+	writeFile(t, filepath.Join(home, "dev", "ha.pid"), []byte(strconv.Itoa(os.Getpid())))
+
+	writeFile(t, filepath.Join(home, "ci", "lima-version"), []byte("0.23.2"))
+	writeFile(t, filepath.Join(home, "ci", "lima.yaml"), []byte("arch: x86_64\n"))
+
+	header := make([]byte, 32)
+	copy(header, "QFI\xfb")
+	binary.BigEndian.PutUint64(header[24:], 10<<30)
+	writeFile(t, filepath.Join(home, "_disks", "data", "datadisk"), header)
+	if err := os.Symlink(filepath.Join(home, "dev"), filepath.Join(home, "_disks", "data", "in_use_by")); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(home)
+	store.hostCapacity = fixedHostCapacity(8, 16<<30)
+
+	instances, err := store.ListInstances()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	byName := map[string]Instance{}
+	for _, instance := range instances {
+		byName[instance.Name] = instance
+	}
+
+	dev := byName["dev"]
+	if dev.Status != StatusRunning || dev.VMType != "vz" || dev.CPUs != 6 || dev.Memory != 8<<30 || dev.Disk != 100<<30 {
+		t.Errorf("unexpected dev instance: %+v", dev)
+	}
+
+	if len(dev.AdditionalDisks) != 1 || dev.AdditionalDisks[0].Name != "data" {
+		t.Errorf("unexpected additional disks: %+v", dev.AdditionalDisks)
+	}
+
+	if len(dev.Config.Mounts) != 1 || !*dev.Config.Mounts[0].Writable || dev.Config.DNS[0] != "1.1.1.1" {
+		t.Errorf("unexpected config: %+v", dev.Config)
+	}
+
+	ci := byName["ci"]
+	if ci.Status != StatusStopped || ci.Arch != "x86_64" || ci.Memory != 4<<30 {
+		t.Errorf("unexpected ci instance: %+v", ci)
+	}
+
+	disks, err := store.ListDisks()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(disks) != 1 || disks[0].Size != 10<<30 || disks[0].Format != "qcow2" || disks[0].Instance != "dev" {
+		t.Errorf("unexpected disks: %+v", disks)
+	}
+}
+
+func fixedHostCapacity(cpus int, memory int64) func() (*HostCapacity, error) {
+	return func() (*HostCapacity, error) {
+		return &HostCapacity{CPUs: cpus, Memory: memory}, nil
+	}
+}
+
+func TestStoreHostDefaults(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "dev", "lima-version"), []byte("1.0.3\n"))
+	writeFile(t, filepath.Join(home, "dev", "lima.yaml"), []byte("vmType: vz\n"))
+
+	tests := map[string]struct {
+		cpus, wantCPUs     int
+		memory, wantMemory int64
+	}{
+		"small host": {cpus: 2, memory: 6 << 30, wantCPUs: 2, wantMemory: 3 << 30},
+		"large host": {cpus: 16, memory: 64 << 30, wantCPUs: 4, wantMemory: 4 << 30},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := NewStore(home)
+			store.hostCapacity = fixedHostCapacity(tt.cpus, tt.memory)
+
+			instances, err := store.ListInstances()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(instances) != 1 || instances[0].CPUs != tt.wantCPUs || instances[0].Memory != tt.wantMemory || instances[0].Disk != 100<<30 {
+				t.Errorf("expected %d CPUs, %d bytes of memory and a 100GiB disk, got %+v", tt.wantCPUs, tt.wantMemory, instances)
+			}
+		})
+	}
+
+	t.Run("unknown host", func(t *testing.T) {
+		store := NewStore(home)
+		store.hostCapacity = func() (*HostCapacity, error) { return nil, errors.New("not supported") }
+
+		if _, err := store.ListInstances(); err == nil {
+			t.Error("expected an error, so that limactl resolves the defaults")
+		}
+	})
+}
+
+func TestStoreLimaDefaults(t *testing.T) {
+	home := t.TempDir()
+
+	for name, limaVersion := range map[string]string{"vz": "1.0.3", "qemu": "1.0.3", "legacy": "0.23.2", "plain": "1.0.3"} {
+		writeFile(t, filepath.Join(home, name, "lima-version"), []byte(limaVersion))
+		writeFile(t, filepath.Join(home, name, "lima.yaml"), []byte("mounts:\n- location: \"~\"\n"))
+	}
+	writeFile(t, filepath.Join(home, "vz", "vz-identifier"), nil)
+	writeFile(t, filepath.Join(home, "plain", "lima.yaml"), []byte("plain: true\nmounts:\n- location: \"~\"\n"))
+
+	store := NewStore(home)
+	store.hostCapacity = fixedHostCapacity(8, 16<<30)
+
+	instances, err := store.ListInstances()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := map[string]struct {
+		vmType, mountType string
+		containerdUser    bool
+		mounts            int
+	}{
+		"vz":     {vmType: "vz", mountType: "virtiofs", containerdUser: true, mounts: 1},
+		"qemu":   {vmType: "qemu", mountType: "9p", containerdUser: true, mounts: 1},
+		"legacy": {vmType: "qemu", mountType: "reverse-sshfs", containerdUser: true, mounts: 1},
+		"plain":  {vmType: "qemu", mountType: "9p", mounts: 0},
+	}
+	if len(instances) != len(tests) {
+		t.Fatalf("expected %d instances, got %+v", len(tests), instances)
+	}
+
+	for _, instance := range instances {
+		tt := tests[instance.Name]
+		cfg := instance.Config
+
+		if instance.VMType != tt.vmType || *cfg.VMType != tt.vmType || *cfg.MountType != tt.mountType {
+			t.Errorf("%s: expected %s with %s mounts, got %s with %s", instance.Name, tt.vmType, tt.mountType, instance.VMType, *cfg.MountType)
+		}
+
+		if *cfg.Containerd.System || *cfg.Containerd.User != tt.containerdUser || len(cfg.Mounts) != tt.mounts {
+			t.Errorf("%s: expected user containerd %t and %d mounts, got %+v", instance.Name, tt.containerdUser, tt.mounts, cfg)
+		}
+
+		if *cfg.Video.Display != "none" || *cfg.Rosetta.Enabled || *cfg.MountInotify {
+			t.Errorf("%s: expected video, rosetta and inotify off, got %+v", instance.Name, cfg)
+		}
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	parse := func(data string) *InstanceConfig {
+		cfg, err := ParseConfig([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	defaults := parse(`
+mounts:
+- location: "~"
+- location: /tmp/lima
+  writable: true
+networks:
+- lima: shared
+  interface: lima0
+- vzNAT: true
+dns: [8.8.8.8]
+video:
+  display: default
+`)
+	cfg := parse(`
+mounts:
+- location: "~/"
+  writable: true
+- location: /data
+networks:
+- lima: bridged
+  interface: lima0
+  metric: 50
+`)
+	override := parse(`
+mounts:
+- location: /data
+  writable: true
+dns: [1.1.1.1]
+plain: true
+`)
+
+	merged := mergeConfig(defaults, cfg, override)
+
+	var mounts []string
+	for _, mount := range merged.Mounts {
+		mounts = append(mounts, fmt.Sprintf("%s:%t", mount.Location, mount.Writable != nil && *mount.Writable))
+	}
+	if want := []string{"~:true", "/tmp/lima:true", "/data:true"}; !slices.Equal(mounts, want) {
+		t.Errorf("expected mounts %v, got %v", want, mounts)
+	}
+
+	if len(merged.Networks) != 2 || merged.Networks[0].Lima != "bridged" || *merged.Networks[0].Metric != 50 ||
+		merged.Networks[1].VZNAT == nil {
+		t.Errorf("expected the bridged network to replace lima0 and vzNAT to be kept, got %+v", merged.Networks)
+	}
+
+	if !slices.Equal(merged.DNS, []string{"1.1.1.1"}) {
+		t.Errorf("expected the dns of override.yaml, got %v", merged.DNS)
+	}
+
+	if merged.Video.Display == nil || *merged.Video.Display != "default" || merged.Plain == nil || !*merged.Plain {
+		t.Errorf("expected video from default.yaml and plain from override.yaml, got %+v", merged)
+	}
+
+	if merged = mergeConfig(defaults, parse("dns: [9.9.9.9]\n"), &InstanceConfig{}); !slices.Equal(merged.DNS, []string{"9.9.9.9"}) {
+		t.Errorf("expected the dns of lima.yaml, got %v", merged.DNS)
+	}
+}
+
+func TestStoreUnsupportedLayout(t *testing.T) {
+	for name, limaVersion := range map[string]string{
+		"missing": "",
+		"future":  "2.0.0",
+	} {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+
+			writeFile(t, filepath.Join(home, "dev", "lima.yaml"), []byte("cpus: 2\n"))
+			if limaVersion != "" {
+				writeFile(t, filepath.Join(home, "dev", "lima-version"), []byte(limaVersion))
+			}
+
+			if _, err := NewStore(home).ListInstances(); !errors.Is(err, ErrUnsupportedLayout) {
+				t.Errorf("expected ErrUnsupportedLayout, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClientFallsBackToLimactl(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, "dev", "lima.yaml"), []byte("cpus: 2\n"))
+
+	client := New(Config{
+		Path:          writeScript(t, `echo '{"name":"from-limactl"}'`),
+		LimaHome:      home,
+		ReadFromStore: true,
+	})
+
+	instances, err := client.ListInstances(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(instances) != 1 || instances[0].Name != "from-limactl" {
+		t.Errorf("expected limactl fallback, got %+v", instances)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"4GiB":   4 << 30,
+		"4G":     4 << 30,
+		"512MiB": 512 << 20,
+		"1.5GiB": 3 << 29,
+		"100":    100,
+	}
+
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseSize("4XB"); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
@@ -0,0 +1,106 @@
+package limactl
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Command is a process started through a Transport.
+type Command struct {
+	// Args holds the program and its arguments.
+	Args []string
+
+	// Env holds variables set in addition to the transport's environment.
+	Env map[string]string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Transport starts commands on the host that runs Lima. ctx only bounds
+// starting the command: once started it runs until it exits or is stopped
+// through Process.Signal, so that the caller decides how to interrupt it.
+type Transport interface {
+	Start(ctx context.Context, cmd *Command) (Process, error)
+}
+
+// Process is a command started by a Transport.
+type Process interface {
+	// Wait waits for the command to exit.
+	Wait() error
+
+	// Signal sends a signal to the command.
+	Signal(sig os.Signal) error
+}
+
+// LocalTransport runs commands on the machine running Terraform.
+type LocalTransport struct{}
+
+func (LocalTransport) Start(ctx context.Context, cmd *Command) (Process, error) {
+	c := exec.Command(cmd.Args[0], cmd.Args[1:]...)
+	c.Env = os.Environ()
+	for k, v := range cmd.Env {
+		c.Env = append(c.Env, k+"="+v)
+	}
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+
+	return &localProcess{cmd: c}, nil
+}
+
+type localProcess struct {
+	cmd *exec.Cmd
+}
+
+func (p *localProcess) Wait() error {
+	return p.cmd.Wait()
+}
+
+func (p *localProcess) Signal(sig os.Signal) error {
+	return p.cmd.Process.Signal(sig)
+}
+
+// CommandLine renders args as a POSIX shell command line, quoting only the
+// arguments that need it.
+func CommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// envAssignments renders env as sorted, quoted NAME=value words.
+func envAssignments(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	words := make([]string, len(keys))
+	for i, k := range keys {
+		words[i] = shellQuote(k + "=" + env[k])
+	}
+
+	return strings.Join(words, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,63 @@
+package limactl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const gib = 1 << 30
+
+// sizeUnits are the suffixes understood by Lima for memory and disk sizes.
+// Like Lima, decimal-looking suffixes are treated as binary multiples.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseSize converts a Lima size such as "4GiB" or "512M" to bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+
+	return int64(math.Round(value * unit)), nil
+}
+
+// BytesToGiB converts bytes to GiB.
+func BytesToGiB(b int64) float64 {
+	return float64(b) / gib
+}
+
+// GiBToBytes converts GiB to bytes.
+func GiBToBytes(g float64) int64 {
+	return int64(math.Round(g * gib))
+}
@@ -0,0 +1,86 @@
+package limactl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// MinimumVersion is the oldest limactl release the provider supports. It is
+// the first release with every flag the resources always pass, such as
+// `--tty=false`, `--set` and `disk create --format`.
+var MinimumVersion = version.Must(version.NewVersion("0.20.0"))
+
+// Feature is a limactl capability that is only available in newer releases.
+type Feature struct {
+	// Name describes the capability, usually the flag it adds.
+	Name string
+
+	// MinVersion is the first limactl release that supports it.
+	MinVersion *version.Version
+}
+
+var (
+	FeatureMountInotify = Feature{Name: "--mount-inotify", MinVersion: version.Must(version.NewVersion("0.21.0"))}
+	FeatureMountNone    = Feature{Name: "--mount-none", MinVersion: version.Must(version.NewVersion("1.0.0"))}
+	FeatureLogFormat    = Feature{Name: "--log-format", MinVersion: version.Must(version.NewVersion("1.0.0"))}
+)
+
+// ParseVersion extracts the version from `limactl --version` output, such as
+// "limactl version 1.0.3". Builds from git ("1.0.3-14-gabcdef") are reduced to
+// the release they are based on.
+func ParseVersion(output string) (*version.Version, error) {
+	fields := strings.Fields(strings.TrimSpace(output))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty version output")
+	}
+
+	v, err := version.NewVersion(fields[len(fields)-1])
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized limactl version %q: %w", strings.TrimSpace(output), err)
+	}
+
+	return v.Core(), nil
+}
+
+// DetectVersion runs `limactl --version` and remembers the result for
+// Supports. It returns the detected version.
+func (c *Client) DetectVersion(ctx context.Context) (*version.Version, error) {
+	output, err := c.Run(ctx, "--version")
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := ParseVersion(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	c.version = v
+
+	return v, nil
+}
+
+// Version returns the version found by DetectVersion, or nil if it is unknown.
+func (c *Client) Version() *version.Version {
+	return c.version
+}
+
+// Supports reports whether the installed limactl has the feature. When the
+// version is unknown the feature is assumed to be available.
+func (c *Client) Supports(f Feature) bool {
+	if c.version == nil {
+		return true
+	}
+
+	return c.version.GreaterThanOrEqual(f.MinVersion)
+}
+
+// jsonLogs reports whether limactl should be run with `--log-format=json`.
+// Unlike Supports it requires a detected version, because an older limactl
+// rejects the flag on every command.
+func (c *Client) jsonLogs() bool {
+	return c.version != nil && c.Supports(FeatureLogFormat)
+}
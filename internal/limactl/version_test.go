@@ -0,0 +1,50 @@
+package limactl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := map[string]string{
+		"limactl version 1.0.3\n":           "1.0.3",
+		"limactl version 0.23.2-14-g1a2b3c": "0.23.2",
+		"limactl version v1.1.0-beta.0":     "1.1.0",
+	}
+
+	for output, want := range cases {
+		got, err := ParseVersion(output)
+		if err != nil {
+			t.Errorf("ParseVersion(%q): unexpected error: %s", output, err)
+			continue
+		}
+
+		if got.String() != want {
+			t.Errorf("ParseVersion(%q) = %s, want %s", output, got, want)
+		}
+	}
+
+	if _, err := ParseVersion("limactl version HEAD-1a2b3c"); err == nil {
+		t.Error("expected error for development build")
+	}
+}
+
+func TestClientSupports(t *testing.T) {
+	client := New(Config{Path: writeScript(t, `echo "limactl version 0.22.0"`)})
+
+	if !client.Supports(FeatureMountNone) {
+		t.Error("features should be assumed available before detection")
+	}
+
+	if _, err := client.DetectVersion(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !client.Supports(FeatureMountInotify) {
+		t.Error("0.22.0 should support --mount-inotify")
+	}
+
+	if client.Supports(FeatureMountNone) {
+		t.Error("0.22.0 should not support --mount-none")
+	}
+}
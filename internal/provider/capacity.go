@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// Lima's defaults for instances that do not set cpus or memory, before they
+// are capped by the host capacity.
+const (
+	limaDefaultCPUs   = 4
+	limaDefaultMemory = 4 << 30
+)
+
+// instanceResources are the CPUs and memory (bytes) of an instance.
+type instanceResources struct {
+	cpus   int64
+	memory int64
+}
+
+// capacityPlanner adds up the planned CPUs and memory of every lima_instance
+// of a plan, together with the running instances that Terraform does not
+// manage, and compares them against the capacity of the host.
+//
+// Terraform plans resources one at a time, so the totals grow as resources
+// are planned and the resource whose plan first exceeds the host gets the
+// diagnostic.
+type capacityPlanner struct {
+	client *limactl.Client
+
+	// maxMemoryOvercommit is the ratio of planned to host memory above which
+	// planning fails, or zero.
+	maxMemoryOvercommit float64
+
+	mu      sync.Mutex
+	loaded  bool
+	host    *limactl.HostCapacity
+	planned map[string]instanceResources
+
+	warnedCPUs   bool
+	warnedMemory bool
+	failed       bool
+}
+
+func newCapacityPlanner(client *limactl.Client, maxMemoryOvercommit float64) *capacityPlanner {
+	return &capacityPlanner{
+		client:              client,
+		maxMemoryOvercommit: maxMemoryOvercommit,
+		planned:             map[string]instanceResources{},
+	}
+}
+
+// plan records the planned CPUs and memory (GiB) of the named instance and
+// reports when the instances of the plan no longer fit on the host. Unknown
+// or unset values count as Lima's defaults.
+func (p *capacityPlanner) plan(ctx context.Context, diags *diag.Diagnostics, name string, cpus types.Int64, memory types.Float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	host := p.hostCapacity(ctx)
+	if host == nil {
+		return
+	}
+
+	planned := instanceResources{
+		cpus:   min(limaDefaultCPUs, int64(host.CPUs)),
+		memory: min(limaDefaultMemory, host.Memory/2),
+	}
+	if !cpus.IsNull() && !cpus.IsUnknown() {
+		planned.cpus = cpus.ValueInt64()
+	}
+	if !memory.IsNull() && !memory.IsUnknown() {
+		planned.memory = limactl.GiBToBytes(memory.ValueFloat64())
+	}
+	p.planned[name] = planned
+
+	p.check(ctx, diags, host)
+}
+
+// destroy records that the named instance is deleted by the plan, so that it
+// is no longer counted as running.
+func (p *capacityPlanner) destroy(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.planned[name] = instanceResources{}
+}
+
+func (p *capacityPlanner) check(ctx context.Context, diags *diag.Diagnostics, host *limactl.HostCapacity) {
+	var total instanceResources
+	for _, r := range p.planned {
+		total.cpus += r.cpus
+		total.memory += r.memory
+	}
+
+	var unmanaged []string
+	instances, err := p.client.Inventory().Instances(ctx)
+	if err != nil {
+		tflog.Debug(ctx, "Listing Lima instances failed, not counting unmanaged instances", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	for _, inst := range instances {
+		if _, ok := p.planned[inst.Name]; ok || inst.Status != limactl.StatusRunning {
+			continue
+		}
+
+		total.cpus += int64(inst.CPUs)
+		total.memory += inst.Memory
+		unmanaged = append(unmanaged, inst.Name)
+	}
+	sort.Strings(unmanaged)
+
+	summary := fmt.Sprintf("The planned instances use %d CPUs and %g GiB of memory", total.cpus, limactl.BytesToGiB(total.memory))
+	if len(unmanaged) > 0 {
+		summary += fmt.Sprintf(", including the running instances not managed by this configuration (%s)", strings.Join(unmanaged, ", "))
+	}
+	summary += fmt.Sprintf(". The host has %d CPUs and %g GiB of memory.", host.CPUs, limactl.BytesToGiB(host.Memory))
+
+	ratio := float64(total.memory) / float64(host.Memory)
+
+	if p.maxMemoryOvercommit > 0 && ratio > p.maxMemoryOvercommit {
+		if !p.failed {
+			p.failed = true
+			diags.AddAttributeError(
+				path.Root("memory"),
+				"Host memory overcommitted",
+				fmt.Sprintf("%s\n\nThat is %.2f times the host memory, more than the provider max_memory_overcommit of %g. "+
+					"Reduce the memory of the instances or stop other instances.", summary, ratio, p.maxMemoryOvercommit),
+			)
+		}
+		return
+	}
+
+	if ratio > 1 && !p.warnedMemory {
+		p.warnedMemory = true
+		diags.AddAttributeWarning(
+			path.Root("memory"),
+			"Host memory overcommitted",
+			fmt.Sprintf("%s\n\nRunning all of them can make the host swap heavily or kill instances when it runs out of memory.", summary),
+		)
+	}
+
+	if total.cpus > int64(host.CPUs) && !p.warnedCPUs {
+		p.warnedCPUs = true
+		diags.AddAttributeWarning(
+			path.Root("cpus"),
+			"Host CPUs overcommitted",
+			fmt.Sprintf("%s\n\nThe instances compete for the host CPUs and may boot and run slowly.", summary),
+		)
+	}
+}
+
+// hostCapacity returns the capacity of the host, or nil when it cannot be
+// determined, in which case nothing is checked.
+func (p *capacityPlanner) hostCapacity(ctx context.Context) *limactl.HostCapacity {
+	if !p.loaded {
+		p.loaded = true
+
+		host, err := p.client.HostCapacity(ctx)
+		if err != nil || host.CPUs <= 0 || host.Memory <= 0 {
+			tflog.Warn(ctx, "Could not determine the host capacity, not checking for overcommit", map[string]any{
+				"error": fmt.Sprint(err),
+			})
+			return nil
+		}
+
+		p.host = host
+	}
+
+	return p.host
+}
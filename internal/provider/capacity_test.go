@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func newTestCapacityPlanner(t *testing.T, maxMemoryOvercommit float64) (*capacityPlanner, *fakeLimactl) {
+	t.Helper()
+
+	fake := newFakeLimactl()
+	fake.instances["unmanaged"] = &limactl.Instance{Name: "unmanaged", Status: limactl.StatusRunning, CPUs: 4, Memory: 8 << 30}
+	fake.instances["stopped"] = &limactl.Instance{Name: "stopped", Status: limactl.StatusStopped, CPUs: 8, Memory: 32 << 30}
+
+	client := limactl.New(limactl.Config{Transport: fake})
+
+	return newCapacityPlanner(client, maxMemoryOvercommit), fake
+}
+
+func TestCapacityPlannerWarns(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestCapacityPlanner(t, 0)
+
+	var diags diag.Diagnostics
+	p.plan(ctx, &diags, "a", types.Int64Value(2), types.Float64Value(4))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics within capacity, got %v", diags)
+	}
+
+	// 4 + 2 + 4 CPUs and 8 + 4 + 6 GiB on a host with 8 CPUs and 16 GiB
+	p.plan(ctx, &diags, "b", types.Int64Value(4), types.Float64Value(6))
+	if len(diags) != 2 || diags.HasError() {
+		t.Fatalf("expected CPU and memory warnings, got %v", diags)
+	}
+
+	// Reported once per plan
+	diags = nil
+	p.plan(ctx, &diags, "c", types.Int64Value(1), types.Float64Value(1))
+	if len(diags) != 0 {
+		t.Errorf("expected the overcommit to be reported once, got %v", diags)
+	}
+}
+
+func TestCapacityPlannerDefaults(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestCapacityPlanner(t, 0)
+
+	var diags diag.Diagnostics
+	p.plan(ctx, &diags, "a", types.Int64Null(), types.Float64Unknown())
+
+	if got := p.planned["a"]; got.cpus != limaDefaultCPUs || got.memory != limaDefaultMemory {
+		t.Errorf("expected Lima's defaults, got %+v", got)
+	}
+}
+
+func TestCapacityPlannerMaxMemoryOvercommit(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestCapacityPlanner(t, 1.5)
+
+	var diags diag.Diagnostics
+	p.plan(ctx, &diags, "a", types.Int64Value(2), types.Float64Value(12))
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Fatalf("expected a memory warning below the ratio, got %v", diags)
+	}
+
+	p.plan(ctx, &diags, "b", types.Int64Value(2), types.Float64Value(6))
+	if diags.ErrorsCount() != 1 {
+		t.Fatalf("expected an error above the ratio, got %v", diags)
+	}
+}
+
+func TestCapacityPlannerDestroy(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestCapacityPlanner(t, 1)
+
+	// The running instance is deleted by the plan and frees its memory.
+	p.destroy("unmanaged")
+
+	var diags diag.Diagnostics
+	p.plan(ctx, &diags, "a", types.Int64Value(8), types.Float64Value(16))
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestCapacityPlannerUnknownHost(t *testing.T) {
+	ctx := context.Background()
+	p, fake := newTestCapacityPlanner(t, 1)
+	fake.host = limactl.HostCapacity{}
+
+	var diags diag.Diagnostics
+	p.plan(ctx, &diags, "a", types.Int64Value(64), types.Float64Value(512))
+	if len(diags) != 0 {
+		t.Errorf("expected no check without a host capacity, got %v", diags)
+	}
+}
@@ -0,0 +1,279 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// instanceDefault describes a lima_instance attribute that the provider
+// defaults block can set.
+type instanceDefault struct {
+	// attribute is the attribute in the defaults block, of the same type as
+	// the resource attribute.
+	attribute schema.Attribute
+
+	// requiresReplace is set for attributes that cannot be changed in place.
+	// Their replacement is decided in ModifyPlan, after the default was
+	// applied, instead of by an attribute plan modifier.
+	requiresReplace bool
+
+	// schemaDefault is set for attributes with a Default in the resource
+	// schema, which applies when neither the resource nor the provider set
+	// them.
+	schemaDefault bool
+}
+
+var instanceDefaults = map[string]instanceDefault{
+	"arch":           {attribute: schema.StringAttribute{Optional: true}, requiresReplace: true},
+	"containerd":     {attribute: schema.StringAttribute{Optional: true}, requiresReplace: true},
+	"cpus":           {attribute: schema.Int64Attribute{Optional: true}},
+	"disk":           {attribute: schema.Float64Attribute{Optional: true}},
+	"dns":            {attribute: schema.ListAttribute{ElementType: types.StringType, Optional: true}},
+	"memory":         {attribute: schema.Float64Attribute{Optional: true}},
+	"mount":          {attribute: schema.ListAttribute{ElementType: types.StringType, Optional: true}},
+	"mount_inotify":  {attribute: schema.BoolAttribute{Optional: true}, schemaDefault: true},
+	"mount_none":     {attribute: schema.BoolAttribute{Optional: true}, requiresReplace: true, schemaDefault: true},
+	"mount_type":     {attribute: schema.StringAttribute{Optional: true}},
+	"mount_writable": {attribute: schema.BoolAttribute{Optional: true}, schemaDefault: true},
+	"network":        {attribute: schema.ListAttribute{ElementType: types.StringType, Optional: true}},
+	"plain":          {attribute: schema.BoolAttribute{Optional: true}, requiresReplace: true, schemaDefault: true},
+	"rosetta":        {attribute: schema.BoolAttribute{Optional: true}, schemaDefault: true},
+	"template":       {attribute: schema.StringAttribute{Optional: true}, requiresReplace: true},
+	"video":          {attribute: schema.BoolAttribute{Optional: true}, schemaDefault: true},
+	"vm_type":        {attribute: schema.StringAttribute{Optional: true}, requiresReplace: true},
+}
+
+func defaultsSchemaAttribute() schema.Attribute {
+	attributes := make(map[string]schema.Attribute, len(instanceDefaults))
+
+	for name, d := range instanceDefaults {
+		description := fmt.Sprintf("Default for the `%s` attribute of `lima_instance`.", name)
+
+		switch a := d.attribute.(type) {
+		case schema.StringAttribute:
+			a.MarkdownDescription = description
+			attributes[name] = a
+		case schema.Int64Attribute:
+			a.MarkdownDescription = description
+			attributes[name] = a
+		case schema.Float64Attribute:
+			a.MarkdownDescription = description
+			attributes[name] = a
+		case schema.BoolAttribute:
+			a.MarkdownDescription = description
+			attributes[name] = a
+		case schema.ListAttribute:
+			a.MarkdownDescription = description
+			attributes[name] = a
+		}
+	}
+
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Values for `lima_instance` attributes that a resource does not set. They are resolved at plan time, so the plan shows the effective values, and changing a default updates or replaces the instances that use it.",
+		Optional:            true,
+		Attributes:          attributes,
+	}
+}
+
+// newInstanceDefaults validates the defaults block and returns the values
+// that are set.
+func newInstanceDefaults(data types.Object) (map[string]attr.Value, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	defaults := map[string]attr.Value{}
+
+	if data.IsNull() {
+		return defaults, diags
+	}
+
+	for name, value := range data.Attributes() {
+		if value.IsUnknown() {
+			diags.AddAttributeError(
+				path.Root("defaults").AtName(name),
+				"Unknown instance default",
+				"Instance defaults must be known when the provider is configured. Set it to a static value or leave it unset.",
+			)
+			continue
+		}
+
+		if !value.IsNull() {
+			defaults[name] = value
+		}
+	}
+
+	return defaults, diags
+}
+
+// applyInstanceDefaults sets every attribute the configuration leaves unset to
+// the provider default, or back to its schema default or null, and requests
+// replacement for changed attributes that cannot be updated in place.
+// Attributes that were not configured when the instance was last applied,
+// such as after an import, keep the value Lima chose.
+func applyInstanceDefaults(ctx context.Context, defaults map[string]attr.Value, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	configured, diags := readConfiguredAttributes(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, name := range instanceDefaultNames() {
+		d := instanceDefaults[name]
+		p := path.Root(name)
+
+		var configValue, planValue, stateValue attr.Value
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, p, &configValue)...)
+		resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, p, &planValue)...)
+		if !req.State.Raw.IsNull() {
+			resp.Diagnostics.Append(req.State.GetAttribute(ctx, p, &stateValue)...)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if configValue.IsNull() {
+			if value, ok := defaults[name]; ok {
+				planValue = value
+			} else if stateValue != nil && !configured.has(name) {
+				planValue = stateValue
+			} else if !d.schemaDefault {
+				planValue = nullValue(ctx, planValue)
+			}
+
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, p, planValue)...)
+		}
+
+		// A null state value, such as after an import, is not known to
+		// differ from the instance.
+		if d.requiresReplace && stateValue != nil && !stateValue.IsNull() && !planValue.Equal(stateValue) {
+			resp.RequiresReplace = append(resp.RequiresReplace, p)
+		}
+	}
+}
+
+// instanceDefaultNames returns the names of the instanceDefaults attributes in
+// order.
+func instanceDefaultNames() []string {
+	names := make([]string, 0, len(instanceDefaults))
+	for name := range instanceDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// configuredKey is the private state key that records the configured
+// attributes of an instance.
+const configuredKey = "configured"
+
+// configuredAttributes is the set of instanceDefaults attributes that the
+// configuration or a provider default set when an instance was last applied.
+// Only those go back to their default or null when they are unset again. A
+// nil set was not recorded and counts every attribute as configured.
+type configuredAttributes map[string]bool
+
+func (c configuredAttributes) has(name string) bool {
+	return c == nil || c[name]
+}
+
+// privateState is the private state of a resource request or response.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// newConfiguredAttributes returns the attributes that config or defaults set.
+func newConfiguredAttributes(ctx context.Context, config tfsdk.Config, defaults map[string]attr.Value) (configuredAttributes, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	configured := configuredAttributes{}
+	for _, name := range instanceDefaultNames() {
+		var value attr.Value
+		diags.Append(config.GetAttribute(ctx, path.Root(name), &value)...)
+
+		_, ok := defaults[name]
+		configured[name] = ok || (value != nil && !value.IsNull())
+	}
+
+	return configured, diags
+}
+
+// stateConfiguredAttributes guesses the configured attributes of an instance
+// that has none recorded from state: imported instances have every attribute
+// null, and earlier versions kept unset attributes null or at their default.
+func stateConfiguredAttributes(ctx context.Context, state tfsdk.State) (configuredAttributes, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	configured := configuredAttributes{}
+	for _, name := range instanceDefaultNames() {
+		var value attr.Value
+		diags.Append(state.GetAttribute(ctx, path.Root(name), &value)...)
+
+		configured[name] = value != nil && !value.IsNull() &&
+			!(instanceDefaults[name].schemaDefault && value.Equal(types.BoolValue(false)))
+	}
+
+	return configured, diags
+}
+
+func readConfiguredAttributes(ctx context.Context, private privateState) (configuredAttributes, diag.Diagnostics) {
+	data, diags := private.GetKey(ctx, configuredKey)
+	if diags.HasError() || data == nil {
+		return nil, diags
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		diags.AddError("Invalid private state", fmt.Sprintf("Could not read the configured attributes of the instance: %s", err))
+		return nil, diags
+	}
+
+	configured := configuredAttributes{}
+	for _, name := range names {
+		configured[name] = true
+	}
+
+	return configured, diags
+}
+
+func writeConfiguredAttributes(ctx context.Context, private privateState, configured configuredAttributes) diag.Diagnostics {
+	names := []string{}
+	for name, ok := range configured {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	data, err := json.Marshal(names)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("Invalid private state", fmt.Sprintf("Could not record the configured attributes of the instance: %s", err))
+		return diags
+	}
+
+	return private.SetKey(ctx, configuredKey, data)
+}
+
+// nullValue returns the null value of v's type.
+func nullValue(ctx context.Context, v attr.Value) attr.Value {
+	typ := v.Type(ctx)
+
+	null, err := typ.ValueFromTerraform(ctx, tftypes.NewValue(typ.TerraformType(ctx), nil))
+	if err != nil {
+		return v
+	}
+
+	return null
+}
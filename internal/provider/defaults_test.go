@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestApplyInstanceDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	r := &LimaInstanceResource{}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objectType, ok := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	if !ok {
+		t.Fatal("schema is not an object")
+	}
+
+	// value builds an instance object, leaving unset attributes null.
+	value := func(attrs map[string]tftypes.Value) tftypes.Value {
+		values := map[string]tftypes.Value{}
+		for name, typ := range objectType.AttributeTypes {
+			values[name] = tftypes.NewValue(typ, nil)
+			if v, ok := attrs[name]; ok {
+				values[name] = v
+			}
+		}
+		return tftypes.NewValue(objectType, values)
+	}
+
+	unknown := tftypes.UnknownValue
+	defaults := map[string]attr.Value{
+		"vm_type": types.StringValue("vz"),
+		"cpus":    types.Int64Value(4),
+		"plain":   types.BoolValue(true),
+	}
+
+	tests := map[string]struct {
+		config, plan, state map[string]tftypes.Value
+		want                map[string]attr.Value
+		replace             path.Paths
+	}{
+		"create": {
+			config: map[string]tftypes.Value{"name": tftypes.NewValue(tftypes.String, "dev"), "cpus": tftypes.NewValue(tftypes.Number, 2)},
+			plan: map[string]tftypes.Value{
+				"name":    tftypes.NewValue(tftypes.String, "dev"),
+				"cpus":    tftypes.NewValue(tftypes.Number, 2),
+				"vm_type": tftypes.NewValue(tftypes.String, unknown),
+				"arch":    tftypes.NewValue(tftypes.String, unknown),
+				"plain":   tftypes.NewValue(tftypes.Bool, false),
+				"video":   tftypes.NewValue(tftypes.Bool, false),
+			},
+			want: map[string]attr.Value{
+				"cpus":    types.Int64Value(2),
+				"vm_type": types.StringValue("vz"),
+				"arch":    types.StringNull(),
+				"plain":   types.BoolValue(true),
+				"video":   types.BoolValue(false),
+			},
+		},
+		"changed default": {
+			config: map[string]tftypes.Value{"name": tftypes.NewValue(tftypes.String, "dev")},
+			plan: map[string]tftypes.Value{
+				"name":    tftypes.NewValue(tftypes.String, "dev"),
+				"cpus":    tftypes.NewValue(tftypes.Number, 2),
+				"vm_type": tftypes.NewValue(tftypes.String, "qemu"),
+				"plain":   tftypes.NewValue(tftypes.Bool, true),
+			},
+			state: map[string]tftypes.Value{
+				"name":    tftypes.NewValue(tftypes.String, "dev"),
+				"cpus":    tftypes.NewValue(tftypes.Number, 2),
+				"vm_type": tftypes.NewValue(tftypes.String, "qemu"),
+				"plain":   tftypes.NewValue(tftypes.Bool, true),
+			},
+			want: map[string]attr.Value{
+				"cpus":    types.Int64Value(4),
+				"vm_type": types.StringValue("vz"),
+			},
+			replace: path.Paths{path.Root("vm_type")},
+		},
+		"removed default": {
+			config: map[string]tftypes.Value{"name": tftypes.NewValue(tftypes.String, "dev"), "vm_type": tftypes.NewValue(tftypes.String, "vz")},
+			plan: map[string]tftypes.Value{
+				"name":    tftypes.NewValue(tftypes.String, "dev"),
+				"vm_type": tftypes.NewValue(tftypes.String, "vz"),
+				"arch":    tftypes.NewValue(tftypes.String, "aarch64"),
+			},
+			state: map[string]tftypes.Value{
+				"name":    tftypes.NewValue(tftypes.String, "dev"),
+				"vm_type": tftypes.NewValue(tftypes.String, "vz"),
+				"arch":    tftypes.NewValue(tftypes.String, "aarch64"),
+			},
+			want: map[string]attr.Value{
+				"vm_type": types.StringValue("vz"),
+				"arch":    types.StringNull(),
+			},
+			replace: path.Paths{path.Root("arch")},
+		},
+		"imported": {
+			config: map[string]tftypes.Value{"name": tftypes.NewValue(tftypes.String, "dev"), "cpus": tftypes.NewValue(tftypes.Number, 4)},
+			plan: map[string]tftypes.Value{
+				"name":       tftypes.NewValue(tftypes.String, "dev"),
+				"cpus":       tftypes.NewValue(tftypes.Number, 4),
+				"mount_none": tftypes.NewValue(tftypes.Bool, false),
+			},
+			state: map[string]tftypes.Value{
+				"name": tftypes.NewValue(tftypes.String, "dev"),
+			},
+			want: map[string]attr.Value{
+				"cpus":       types.Int64Value(4),
+				"vm_type":    types.StringValue("vz"),
+				"mount_none": types.BoolValue(false),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := tftypes.NewValue(objectType, nil)
+			if test.state != nil {
+				state = value(test.state)
+			}
+
+			req := resource.ModifyPlanRequest{
+				Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: value(test.config)},
+				Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: value(test.plan)},
+				State:  tfsdk.State{Schema: schemaResp.Schema, Raw: state},
+			}
+			resp := resource.ModifyPlanResponse{Plan: req.Plan}
+
+			applyInstanceDefaults(ctx, defaults, req, &resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+
+			for name, want := range test.want {
+				var got attr.Value
+				resp.Plan.GetAttribute(ctx, path.Root(name), &got)
+				if !got.Equal(want) {
+					t.Errorf("%s: expected %s, got %s", name, want, got)
+				}
+			}
+
+			if len(resp.RequiresReplace) != len(test.replace) {
+				t.Fatalf("expected replacement for %v, got %v", test.replace, resp.RequiresReplace)
+			}
+			for i := range test.replace {
+				if !resp.RequiresReplace[i].Equal(test.replace[i]) {
+					t.Errorf("expected replacement for %v, got %v", test.replace, resp.RequiresReplace)
+				}
+			}
+		})
+	}
+}
+
+func TestLimaInstanceResourceModifyPlanUnconfigured(t *testing.T) {
+	ctx := context.Background()
+
+	r := &LimaInstanceResource{}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["name"] = tftypes.NewValue(tftypes.String, "dev")
+	config := tftypes.NewValue(objectType, maps.Clone(values))
+
+	values["arch"] = tftypes.NewValue(tftypes.String, tftypes.UnknownValue)
+	values["cpus"] = tftypes.NewValue(tftypes.Number, tftypes.UnknownValue)
+
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: config},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+		State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+	}
+	resp := resource.ModifyPlanResponse{Plan: req.Plan}
+
+	r.ModifyPlan(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	for _, name := range []string{"arch", "cpus"} {
+		var got attr.Value
+		resp.Plan.GetAttribute(ctx, path.Root(name), &got)
+		if !got.IsNull() {
+			t.Errorf("%s: expected null without the provider configuration, got %s", name, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// errorRemedy is the summary and remediation reported for a class of limactl
+// failures.
+type errorRemedy struct {
+	summary string
+	remedy  string
+}
+
+var errorRemedies = map[limactl.ErrorClass]errorRemedy{
+	limactl.ErrorBinaryNotFound: {
+		summary: "limactl not found",
+		remedy:  "limactl could not be run. Install Lima (https://lima-vm.io/docs/installation/) or set the provider limactl_path to the limactl binary. With the ssh block, the path refers to the remote host.",
+	},
+	limactl.ErrorInstanceExists: {
+		summary: "instance already exists",
+		remedy:  "An instance of this name already exists outside of Terraform. Import it with `terraform import`, delete it with `limactl delete`, or choose another name.",
+	},
+	limactl.ErrorNotFound: {
+		summary: "instance not found",
+		remedy:  "The instance no longer exists, it was probably deleted outside of Terraform. Run `terraform apply -refresh-only` to update the state.",
+	},
+	limactl.ErrorDiskNotFound: {
+		summary: "disk not found",
+		remedy:  "The disk does not exist. Disks attached to an instance must be created first, for example by a lima_disk resource that the instance references. If it was deleted outside of Terraform, run `terraform apply -refresh-only` to update the state.",
+	},
+	limactl.ErrorDiskInUse: {
+		summary: "disk in use",
+		remedy:  "The disk is attached to another instance. Detach it from that instance, or stop and delete the instance, before using the disk here.",
+	},
+	limactl.ErrorUnsupportedVMType: {
+		summary: "unsupported vm_type",
+		remedy:  "This host cannot run the requested vm_type. `vz` requires macOS 13 or later, `wsl2` requires Windows. Use `qemu` or leave vm_type unset to use Lima's default.",
+	},
+	limactl.ErrorUnsupportedArch: {
+		summary: "unsupported arch",
+		remedy:  "This host cannot run the requested architecture. Foreign architectures need the matching QEMU system emulator (for example qemu-system-aarch64) and vm_type `qemu`. Leave arch unset to use the host architecture.",
+	},
+	limactl.ErrorTemplateFetch: {
+		summary: "template not available",
+		remedy:  "The template could not be found or downloaded. Check its name with `limactl create --list-templates`, the path or URL, and the network connection of the host.",
+	},
+	limactl.ErrorNoSpace: {
+		summary: "insufficient disk space",
+		remedy:  "The host ran out of disk space. Free up space in LIMA_HOME, for example by deleting unused instances and clearing the image cache with `limactl prune`, or reduce the disk size.",
+	},
+	limactl.ErrorNetworkPermission: {
+		summary: "network permissions missing",
+		remedy:  "Shared and bridged networks need the sudoers file for Lima. Install it with `limactl sudoers | sudo tee /etc/sudoers.d/lima` and check the paths in networks.yaml (https://lima-vm.io/docs/config/network/vmnet/).",
+	},
+}
+
+// instanceErrorAttributes are the lima_instance attributes that recognised
+// failures are reported on.
+var instanceErrorAttributes = map[limactl.ErrorClass]path.Path{
+	limactl.ErrorInstanceExists:    path.Root("name"),
+	limactl.ErrorNotFound:          path.Root("name"),
+	limactl.ErrorDiskNotFound:      path.Root("disks"),
+	limactl.ErrorDiskInUse:         path.Root("disks"),
+	limactl.ErrorUnsupportedVMType: path.Root("vm_type"),
+	limactl.ErrorUnsupportedArch:   path.Root("arch"),
+	limactl.ErrorTemplateFetch:     path.Root("template"),
+	limactl.ErrorNoSpace:           path.Root("disk"),
+	limactl.ErrorNetworkPermission: path.Root("network"),
+}
+
+// diskErrorAttributes are the lima_disk attributes that recognised failures
+// are reported on.
+var diskErrorAttributes = map[limactl.ErrorClass]path.Path{
+	limactl.ErrorDiskNotFound: path.Root("name"),
+	limactl.ErrorDiskInUse:    path.Root("name"),
+	limactl.ErrorNoSpace:      path.Root("size"),
+}
+
+// addLimactlError adds an error for a failed limactl command. Recognised
+// failures get a specific summary and a remedy, and are reported on the
+// attribute in attributes that caused them.
+func addLimactlError(diags *diag.Diagnostics, summary string, err error, attributes map[limactl.ErrorClass]path.Path) {
+	class := limactl.Classify(err)
+
+	remedy, ok := errorRemedies[class]
+	if !ok {
+		diags.AddError(summary, err.Error())
+		return
+	}
+
+	summary = summary + ": " + remedy.summary
+	detail := fmt.Sprintf("%s\n\n%s", remedy.remedy, err)
+
+	if attribute, ok := attributes[class]; ok {
+		diags.AddAttributeError(attribute, summary, detail)
+		return
+	}
+
+	diags.AddError(summary, detail)
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func TestAddLimactlError(t *testing.T) {
+	var diags diag.Diagnostics
+	addLimactlError(&diags, "Failed to create Lima instance", &limactl.CommandError{
+		Args:     []string{"create", "--name=dev", "--vm-type=vz"},
+		Messages: []string{"VZ driver requires macOS 13 or higher to run"},
+	}, instanceErrorAttributes)
+
+	if len(diags) != 1 {
+		t.Fatalf("expected one diagnostic, got %v", diags)
+	}
+
+	withPath, ok := diags[0].(diag.DiagnosticWithPath)
+	if !ok || !withPath.Path().Equal(path.Root("vm_type")) {
+		t.Errorf("expected the error on vm_type, got %v", diags[0])
+	}
+
+	if got := diags[0].Summary(); got != "Failed to create Lima instance: unsupported vm_type" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	if detail := diags[0].Detail(); !strings.Contains(detail, "macOS 13") || !strings.Contains(detail, "limactl create --name=dev") {
+		t.Errorf("expected the remedy and the command in the detail, got %q", detail)
+	}
+}
+
+func TestAddLimactlErrorWithoutAttribute(t *testing.T) {
+	var diags diag.Diagnostics
+	addLimactlError(&diags, "Failed to create Lima disk", &limactl.CommandError{
+		Messages: []string{"sudoers file not found"},
+	}, diskErrorAttributes)
+
+	if len(diags) != 1 || !strings.HasSuffix(diags[0].Summary(), ": network permissions missing") {
+		t.Fatalf("expected a classified error, got %v", diags)
+	}
+
+	if _, ok := diags[0].(diag.DiagnosticWithPath); ok {
+		t.Errorf("expected no attribute path, got %v", diags[0])
+	}
+}
+
+func TestAddLimactlErrorUnknown(t *testing.T) {
+	var diags diag.Diagnostics
+	addLimactlError(&diags, "Failed to delete Lima disk", errors.New("boom"), diskErrorAttributes)
+
+	if len(diags) != 1 || diags[0].Summary() != "Failed to delete Lima disk" || diags[0].Detail() != "boom" {
+		t.Errorf("expected the error unchanged, got %v", diags)
+	}
+}
+
+func TestErrorRemediesComplete(t *testing.T) {
+	for class := limactl.ErrorBinaryNotFound; class <= limactl.ErrorNetworkPermission; class++ {
+		if _, ok := errorRemedies[class]; !ok {
+			t.Errorf("no remedy for error class %d", class)
+		}
+	}
+}
+
+func TestAddLimactlErrorMissingDisk(t *testing.T) {
+	var diags diag.Diagnostics
+	addLimactlError(&diags, "Failed to create Lima instance", &limactl.CommandError{
+		Messages: []string{`disk "data" does not exist`},
+	}, instanceErrorAttributes)
+
+	if len(diags) != 1 || diags[0].Summary() != "Failed to create Lima instance: disk not found" {
+		t.Fatalf("expected a missing disk error, got %v", diags)
+	}
+
+	withPath, ok := diags[0].(diag.DiagnosticWithPath)
+	if !ok || !withPath.Path().Equal(path.Root("disks")) {
+		t.Errorf("expected the error on disks, got %v", diags[0])
+	}
+}
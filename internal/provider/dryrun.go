@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// dryRunEnvVar enables dry-run mode when dry_run is not set in the provider
+// configuration.
+const dryRunEnvVar = "LIMA_DRY_RUN"
+
+// addDryRunWarning reports the limactl commands that dry-run mode skipped.
+func addDryRunWarning(diags *diag.Diagnostics, rec *limactl.Recorder) {
+	commands := rec.Commands()
+	if len(commands) == 0 {
+		return
+	}
+
+	diags.AddWarning(
+		"Dry run: limactl commands not executed",
+		"The provider is in dry-run mode. These commands would have run:\n\n"+strings.Join(commands, "\n"),
+	)
+}
@@ -0,0 +1,865 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// fakeLimactl is an in-memory stand-in for limactl, injected as the transport
+// of the provider. It keeps a model of instances and disks and answers the
+// commands the provider runs the way limactl 1.0 does.
+type fakeLimactl struct {
+	mu sync.Mutex
+
+	version   string
+	host      limactl.HostCapacity
+	instances map[string]*limactl.Instance
+	disks     map[string]*limactl.Disk
+	files     map[string][]byte
+	failures  map[string][]string
+	commands  [][]string
+}
+
+func newFakeLimactl() *fakeLimactl {
+	return &fakeLimactl{
+		version:   "1.0.3",
+		host:      limactl.HostCapacity{CPUs: 8, Memory: 16 << 30},
+		instances: map[string]*limactl.Instance{},
+		disks:     map[string]*limactl.Disk{},
+		files:     map[string][]byte{},
+		failures:  map[string][]string{},
+	}
+}
+
+// fakeProtoV6ProviderFactories returns provider factories that run limactl
+// commands against fake.
+func fakeProtoV6ProviderFactories(fake *fakeLimactl) map[string]func() (tfprotov6.ProviderServer, error) {
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"lima": providerserver.NewProtocol6WithError(newFakeProvider(fake)()),
+	}
+}
+
+// newFakeProvider returns providers whose limactl client runs against fake.
+// The provider configuration is validated as usual, so a limactl stub is put
+// on PATH for the lookup.
+func newFakeProvider(fake *fakeLimactl) func() provider.Provider {
+	fakeLimactlPathOnce.Do(installFakeLimactlPath)
+
+	return New("test", withTransport(fake))
+}
+
+// withTransport makes the provider run limactl through transport.
+func withTransport(transport limactl.Transport) Option {
+	return func(p *LimaProvider) {
+		p.transport = transport
+	}
+}
+
+var fakeLimactlPathOnce sync.Once
+
+// installFakeLimactlPath puts a limactl stub, which is never run, first on
+// PATH.
+func installFakeLimactlPath() {
+	dir, err := os.MkdirTemp("", "fake-limactl")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "limactl"), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		panic(err)
+	}
+
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// Fail makes the next run of command, such as "start" or "disk resize", fail
+// with message. Calling it several times queues several failures.
+func (f *fakeLimactl) Fail(command string, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[command] = append(f.failures[command], message)
+}
+
+// Instance returns a copy of the named instance, or nil.
+func (f *fakeLimactl) Instance(name string) *limactl.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[name]
+	if !ok {
+		return nil
+	}
+
+	c := *inst
+	return &c
+}
+
+// Disk returns a copy of the named disk, or nil.
+func (f *fakeLimactl) Disk(name string) *limactl.Disk {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	disk, ok := f.disks[name]
+	if !ok {
+		return nil
+	}
+
+	c := *disk
+	return &c
+}
+
+// Update changes an instance out of band, as if someone used limactl by hand.
+func (f *fakeLimactl) Update(name string, update func(*limactl.Instance)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if inst, ok := f.instances[name]; ok {
+		update(inst)
+	}
+}
+
+// Remove deletes an instance or disk out of band.
+func (f *fakeLimactl) Remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if inst, ok := f.instances[name]; ok {
+		f.removeFiles(inst.Dir)
+	}
+	if disk, ok := f.disks[name]; ok {
+		f.removeFiles(disk.Dir)
+	}
+
+	delete(f.instances, name)
+	delete(f.disks, name)
+}
+
+// File returns the content of a file the provider wrote, or nil.
+func (f *fakeLimactl) File(path string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.files[path]
+}
+
+// removeFiles deletes the files in dir, as deleting an instance or disk
+// deletes its directory.
+func (f *fakeLimactl) removeFiles(dir string) {
+	for path := range f.files {
+		if strings.HasPrefix(path, dir+"/") {
+			delete(f.files, path)
+		}
+	}
+}
+
+// Commands returns the limactl subcommands run so far, without global flags.
+func (f *fakeLimactl) Commands() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][]string(nil), f.commands...)
+}
+
+func (f *fakeLimactl) Start(ctx context.Context, cmd *limactl.Command) (limactl.Process, error) {
+	args := cmd.Args[1:]
+
+	// The shell scripts the provider runs report the host capacity and read
+	// and write ownership markers. Instance logs are never found.
+	if cmd.Args[0] == "sh" {
+		script := cmd.Args[2]
+		switch {
+		case strings.Contains(script, "_NPROCESSORS_ONLN"):
+			fmt.Fprintf(cmd.Stdout, "%d\n%d\n", f.host.CPUs, f.host.Memory)
+		case strings.HasPrefix(script, "cat >"):
+			data, err := io.ReadAll(cmd.Stdin)
+			if err != nil {
+				return nil, err
+			}
+
+			f.mu.Lock()
+			f.files[cmd.Args[4]] = data
+			f.mu.Unlock()
+		case strings.HasPrefix(script, `[ -f "$1" ]`):
+			f.mu.Lock()
+			_, _ = cmd.Stdout.Write(f.files[cmd.Args[4]])
+			f.mu.Unlock()
+		}
+		return fakeProcess{}, nil
+	}
+
+	jsonLogs := len(args) > 0 && args[0] == "--log-format=json"
+	if jsonLogs {
+		args = args[1:]
+	}
+
+	home := cmd.Env["LIMA_HOME"]
+	if home == "" {
+		home = "/fake/lima"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands = append(f.commands, args)
+
+	err := f.injectedFailure(args)
+	if err == nil {
+		err = f.run(home, args, cmd.Stdout)
+	}
+
+	if err != nil {
+		msg := err.Error()
+		if jsonLogs {
+			line, _ := json.Marshal(map[string]string{"level": "fatal", "msg": msg, "time": "2024-01-01T00:00:00Z"})
+			msg = string(line)
+		}
+		fmt.Fprintln(cmd.Stderr, msg)
+
+		return fakeProcess{err: fakeExitError{}}, nil
+	}
+
+	return fakeProcess{}, nil
+}
+
+func (f *fakeLimactl) injectedFailure(args []string) error {
+	for _, key := range []string{strings.Join(args[:min(2, len(args))], " "), args[0]} {
+		if queue := f.failures[key]; len(queue) > 0 {
+			f.failures[key] = queue[1:]
+			return fmt.Errorf("%s", queue[0])
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeLimactl) run(home string, args []string, stdout io.Writer) error {
+	switch args[0] {
+	case "--version":
+		fmt.Fprintf(stdout, "limactl version %s\n", f.version)
+		return nil
+	case "list":
+		return f.list(stdout)
+	case "create":
+		return f.create(home, args[1:])
+	case "start", "stop", "edit", "delete":
+		inst, ok := f.instances[args[1]]
+		if !ok {
+			return fmt.Errorf("instance %q does not exist, run `limactl create %s` to create a new instance", args[1], args[1])
+		}
+
+		return f.instanceCommand(args[0], inst, args[2:])
+	case "disk":
+		return f.disk(home, args[1:], stdout)
+	}
+
+	return fmt.Errorf("unknown command %q for \"limactl\"", args[0])
+}
+
+func (f *fakeLimactl) list(stdout io.Writer) error {
+	names := make([]string, 0, len(f.instances))
+	for name := range f.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		line, err := json.Marshal(f.instances[name])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", line)
+	}
+
+	return nil
+}
+
+func (f *fakeLimactl) create(home string, args []string) error {
+	yes, no, display := true, false, "none"
+	inst := &limactl.Instance{
+		Status: limactl.StatusStopped,
+		VMType: "qemu",
+		Arch:   "x86_64",
+		CPUs:   4,
+		Memory: 4 << 30,
+		Disk:   100 << 30,
+		// The default template with the defaults filled in and ~ expanded,
+		// as limactl list reports it
+		Config: &limactl.InstanceConfig{
+			Mounts: []limactl.Mount{
+				{Location: "/Users/me"},
+				{Location: "/tmp/lima", Writable: &yes},
+			},
+			MountInotify: &no,
+			Containerd:   limactl.Containerd{System: &no, User: &yes},
+			Video:        limactl.Video{Display: &display},
+			Rosetta:      limactl.Rosetta{Enabled: &no, BinFmt: &no},
+			Plain:        &no,
+		},
+	}
+
+	for _, arg := range args {
+		name, ok := strings.CutPrefix(arg, "--name=")
+		if !ok {
+			continue
+		}
+
+		if _, exists := f.instances[name]; exists {
+			return fmt.Errorf("instance %q already exists", name)
+		}
+
+		inst.Name = name
+		inst.Dir = home + "/" + name
+	}
+
+	if inst.Name == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	if err := f.apply(inst, args); err != nil {
+		return err
+	}
+
+	f.instances[inst.Name] = inst
+	return nil
+}
+
+func (f *fakeLimactl) instanceCommand(command string, inst *limactl.Instance, args []string) error {
+	switch command {
+	case "start":
+		inst.Status = limactl.StatusRunning
+	case "stop":
+		inst.Status = limactl.StatusStopped
+	case "edit":
+		if inst.Status == limactl.StatusRunning {
+			return fmt.Errorf("cannot edit a running instance %q", inst.Name)
+		}
+		return f.apply(inst, args)
+	case "delete":
+		if inst.Status == limactl.StatusRunning && !contains(args, "--force") {
+			return fmt.Errorf("expected status %q, got %q (maybe use `limactl stop %s`?)", limactl.StatusStopped, inst.Status, inst.Name)
+		}
+
+		for _, disk := range f.disks {
+			if disk.Instance == inst.Name {
+				disk.Instance, disk.InstanceDir, disk.MountPoint = "", "", ""
+			}
+		}
+
+		f.removeFiles(inst.Dir)
+		delete(f.instances, inst.Name)
+	}
+
+	return nil
+}
+
+// apply applies create and edit flags to inst.
+func (f *fakeLimactl) apply(inst *limactl.Instance, args []string) error {
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+
+		switch key {
+		case "--cpus":
+			cpus, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid argument %q for \"--cpus\" flag", value)
+			}
+			inst.CPUs = cpus
+			inst.Config.CPUs = &cpus
+		case "--memory":
+			gib, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid argument %q for \"--memory\" flag", value)
+			}
+			inst.Memory = limactl.GiBToBytes(gib)
+			memory := value + "GiB"
+			inst.Config.Memory = &memory
+		case "--disk":
+			gib, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid argument %q for \"--disk\" flag", value)
+			}
+			inst.Disk = limactl.GiBToBytes(gib)
+			disk := value + "GiB"
+			inst.Config.Disk = &disk
+		case "--vm-type":
+			inst.VMType = value
+			inst.Config.VMType = &value
+		case "--arch":
+			inst.Arch = value
+			inst.Config.Arch = &value
+		case "--mount-type":
+			inst.Config.MountType = &value
+		case "--dns":
+			if !slices.Contains(inst.Config.DNS, value) {
+				inst.Config.DNS = append(inst.Config.DNS, value)
+			}
+		case "--mount":
+			location, w := strings.CutSuffix(value, ":w")
+			location = strings.Replace(location, "~", "/Users/me", 1)
+			inst.Config.Mounts = slices.DeleteFunc(inst.Config.Mounts, func(m limactl.Mount) bool {
+				return m.Location == location
+			})
+			inst.Config.Mounts = append(inst.Config.Mounts, limactl.Mount{Location: location, Writable: &w})
+		case "--network":
+			var network limactl.Network
+			if lima, ok := strings.CutPrefix(value, "lima:"); ok {
+				network.Lima = lima
+			} else {
+				vzNAT := true
+				network.VZNAT = &vzNAT
+			}
+			inst.Config.Networks = append(inst.Config.Networks, network)
+		case "--containerd":
+			system, user := strings.Contains(value, "system"), strings.Contains(value, "user")
+			inst.Config.Containerd = limactl.Containerd{System: &system, User: &user}
+		case "--plain":
+			plain := true
+			inst.Config.Plain = &plain
+		case "--mount-inotify":
+			inotify := true
+			inst.Config.MountInotify = &inotify
+		case "--mount-none":
+			inst.Config.Mounts = nil
+		case "--mount-writable":
+			for i := range inst.Config.Mounts {
+				writable := true
+				inst.Config.Mounts[i].Writable = &writable
+			}
+		case "--video":
+			display := "default"
+			inst.Config.Video.Display = &display
+		case "--rosetta":
+			enabled := true
+			inst.Config.Rosetta.Enabled = &enabled
+		case "--set":
+			for _, expr := range splitPipeline(value) {
+				if err := f.set(inst, expr); err != nil {
+					return fmt.Errorf("failed to parse --set expression: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// fakeSelect matches the conditions of the del expressions of the provider.
+var fakeSelect = regexp.MustCompile(`\.(location|lima|vzNAT) == ("[^"]*"|true)`)
+
+// set applies one of the yq expressions the provider passes to --set.
+func (f *fakeLimactl) set(inst *limactl.Instance, expr string) error {
+	cfg := inst.Config
+
+	if conditions, ok := strings.CutPrefix(expr, "del(.mounts[] | select("); ok {
+		for _, m := range fakeSelect.FindAllStringSubmatch(conditions, -1) {
+			var location string
+			if err := json.Unmarshal([]byte(m[2]), &location); err != nil {
+				return err
+			}
+			location = strings.Replace(location, "~", "/Users/me", 1)
+
+			cfg.Mounts = slices.DeleteFunc(cfg.Mounts, func(mount limactl.Mount) bool {
+				return mount.Location == location
+			})
+		}
+		return nil
+	}
+
+	if conditions, ok := strings.CutPrefix(expr, "(.mounts[] | select("); ok {
+		conditions, value, _ := strings.Cut(conditions, ")).writable=")
+		writable := value == "true"
+		for _, m := range fakeSelect.FindAllStringSubmatch(conditions, -1) {
+			var location string
+			if err := json.Unmarshal([]byte(m[2]), &location); err != nil {
+				return err
+			}
+			location = strings.Replace(location, "~", "/Users/me", 1)
+
+			for i := range cfg.Mounts {
+				if cfg.Mounts[i].Location == location {
+					cfg.Mounts[i].Writable = &writable
+				}
+			}
+		}
+		return nil
+	}
+
+	if value, ok := strings.CutPrefix(expr, ".mounts[].writable="); ok {
+		writable := value == "true"
+		for i := range cfg.Mounts {
+			cfg.Mounts[i].Writable = &writable
+		}
+		return nil
+	}
+
+	if expr == ".mounts |= unique_by(.location)" {
+		seen := map[string]bool{}
+		cfg.Mounts = slices.DeleteFunc(cfg.Mounts, func(mount limactl.Mount) bool {
+			duplicate := seen[mount.Location]
+			seen[mount.Location] = true
+			return duplicate
+		})
+		return nil
+	}
+
+	if conditions, ok := strings.CutPrefix(expr, "del(.networks[] | select("); ok {
+		for _, m := range fakeSelect.FindAllStringSubmatch(conditions, -1) {
+			cfg.Networks = slices.DeleteFunc(cfg.Networks, func(network limactl.Network) bool {
+				if m[1] == "vzNAT" {
+					return network.VZNAT != nil && *network.VZNAT
+				}
+				return m[2] == strconv.Quote(network.Lima)
+			})
+		}
+		return nil
+	}
+
+	if value, ok := strings.CutPrefix(expr, ".mounts += "); ok {
+		var mounts []limactl.Mount
+		if err := json.Unmarshal([]byte(value), &mounts); err != nil {
+			return err
+		}
+		for i := range mounts {
+			mounts[i].Location = strings.Replace(mounts[i].Location, "~", "/Users/me", 1)
+		}
+		cfg.Mounts = append(cfg.Mounts, mounts...)
+		return nil
+	}
+
+	if value, ok := strings.CutPrefix(expr, ".networks += "); ok {
+		var networks []limactl.Network
+		if err := json.Unmarshal([]byte(value), &networks); err != nil {
+			return err
+		}
+		cfg.Networks = append(cfg.Networks, networks...)
+		return nil
+	}
+
+	// Removed resources go back to those of the default template
+	switch expr {
+	case "del(.cpus)":
+		inst.CPUs, cfg.CPUs = 4, nil
+		return nil
+	case "del(.memory)":
+		inst.Memory, cfg.Memory = 4<<30, nil
+		return nil
+	case "del(.disk)":
+		inst.Disk, cfg.Disk = 100<<30, nil
+		return nil
+	case "del(.mountType)":
+		cfg.MountType = nil
+		return nil
+	}
+
+	key, value, _ := strings.Cut(expr, "=")
+	enabled := value == "true"
+
+	switch key {
+	case ".dns":
+		cfg.DNS = nil
+		return json.Unmarshal([]byte(value), &cfg.DNS)
+	case ".hostResolver.enabled", ".rosetta.binfmt":
+	case ".mountInotify":
+		cfg.MountInotify = &enabled
+	case ".rosetta.enabled":
+		cfg.Rosetta.Enabled = &enabled
+	case ".video.display":
+		var display string
+		if err := json.Unmarshal([]byte(value), &display); err != nil {
+			return err
+		}
+		cfg.Video.Display = &display
+	case ".additionalDisks":
+		var additional []limactl.AdditionalDisk
+		if err := json.Unmarshal([]byte(value), &additional); err != nil {
+			return err
+		}
+
+		for _, disk := range f.disks {
+			if disk.Instance == inst.Name {
+				disk.Instance, disk.InstanceDir, disk.MountPoint = "", "", ""
+			}
+		}
+
+		for _, ad := range additional {
+			disk, ok := f.disks[ad.Name]
+			if !ok {
+				return fmt.Errorf("disk %q does not exist", ad.Name)
+			}
+
+			disk.Instance = inst.Name
+			disk.InstanceDir = inst.Dir
+			disk.MountPoint = "/mnt/lima-" + ad.Name
+		}
+
+		inst.AdditionalDisks = additional
+		cfg.AdditionalDisks = additional
+	default:
+		return fmt.Errorf("unsupported expression %q", expr)
+	}
+
+	return nil
+}
+
+// splitPipeline splits a yq expression at the pipes outside of parentheses
+// and strings.
+func splitPipeline(expr string) []string {
+	var parts []string
+	var depth, start int
+	var quoted bool
+
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '"' && (i == 0 || expr[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0 && strings.HasPrefix(expr[i:], "| "):
+			parts = append(parts, strings.TrimSpace(expr[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(expr[start:]))
+}
+
+func (f *fakeLimactl) disk(home string, args []string, stdout io.Writer) error {
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(f.disks))
+		for name := range f.disks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			line, err := json.Marshal(f.disks[name])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%s\n", line)
+		}
+
+		return nil
+	case "create":
+		name := args[1]
+		if _, exists := f.disks[name]; exists {
+			return fmt.Errorf("disk %q already exists", name)
+		}
+
+		size, err := diskSizeFlag(args)
+		if err != nil {
+			return err
+		}
+
+		format := "qcow2"
+		for _, arg := range args {
+			if value, ok := strings.CutPrefix(arg, "--format="); ok {
+				format = value
+			}
+		}
+
+		f.disks[name] = &limactl.Disk{Name: name, Size: size, Format: format, Dir: home + "/_disks/" + name}
+		return nil
+	}
+
+	disk, ok := f.disks[args[1]]
+	if !ok {
+		return fmt.Errorf("disk %q does not exist", args[1])
+	}
+
+	switch args[0] {
+	case "resize":
+		size, err := diskSizeFlag(args)
+		if err != nil {
+			return err
+		}
+
+		if size < disk.Size {
+			return fmt.Errorf("specified size %d is less than the current disk size %d", size, disk.Size)
+		}
+
+		if disk.Instance != "" {
+			if inst, ok := f.instances[disk.Instance]; ok && inst.Status == limactl.StatusRunning {
+				return fmt.Errorf("cannot resize disk %q used by running instance %q", disk.Name, disk.Instance)
+			}
+		}
+
+		disk.Size = size
+		return nil
+	case "delete":
+		if disk.Instance != "" && !contains(args, "--force") {
+			return fmt.Errorf("cannot delete disk %q in use by instance %q", disk.Name, disk.Instance)
+		}
+
+		f.removeFiles(disk.Dir)
+		delete(f.disks, disk.Name)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q for \"limactl disk\"", args[0])
+}
+
+func diskSizeFlag(args []string) (int64, error) {
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--size="); ok {
+			return limactl.ParseSize(value)
+		}
+	}
+
+	return 0, fmt.Errorf("--size is required")
+}
+
+func contains(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fakeProcess is a command that already ran.
+type fakeProcess struct {
+	err error
+}
+
+func (p fakeProcess) Wait() error {
+	return p.err
+}
+
+func (p fakeProcess) Signal(sig os.Signal) error {
+	return nil
+}
+
+type fakeExitError struct{}
+
+func (fakeExitError) Error() string {
+	return "exit status 1"
+}
+
+func (fakeExitError) ExitCode() int {
+	return 1
+}
+
+func TestFakeLimactl(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeLimactl()
+	client := limactl.New(limactl.Config{Transport: fake, Retry: &limactl.RetryPolicy{MaxAttempts: 1}})
+
+	if _, err := client.DetectVersion(ctx); err != nil {
+		t.Fatalf("DetectVersion: %v", err)
+	}
+
+	if err := client.CreateDisk(ctx, "data", 10, ""); err != nil {
+		t.Fatalf("CreateDisk: %v", err)
+	}
+
+	flags := []string{"--cpus=2", "--memory=4", `--set=.additionalDisks=[{"name":"data"}]`}
+	if err := client.CreateInstance(ctx, "test", "", flags); err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+
+	if err := client.StartInstance(ctx, "test"); err != nil {
+		t.Fatalf("StartInstance: %v", err)
+	}
+
+	inst, err := client.GetInstance(ctx, "test")
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	if inst.Status != limactl.StatusRunning || inst.CPUs != 2 || inst.Memory != 4<<30 {
+		t.Errorf("unexpected instance %+v", inst)
+	}
+
+	disk, err := client.GetDisk(ctx, "data")
+	if err != nil {
+		t.Fatalf("GetDisk: %v", err)
+	}
+	if disk.Instance != "test" {
+		t.Errorf("expected disk to be attached to test, got %q", disk.Instance)
+	}
+
+	// limactl refuses to delete a running instance
+	err = client.DeleteInstance(ctx, "test")
+	var cmdErr *limactl.CommandError
+	if !errors.As(err, &cmdErr) || !strings.Contains(cmdErr.Error(), "limactl stop test") {
+		t.Fatalf("expected a CommandError suggesting limactl stop, got %v", err)
+	}
+	if code := limactl.ExitCode(err); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+
+	fake.Fail("stop", "instance is broken")
+	err = client.StopInstance(ctx, "test")
+	if !errors.As(err, &cmdErr) || len(cmdErr.Messages) != 1 || cmdErr.Messages[0] != "instance is broken" {
+		t.Fatalf("expected the injected failure as a JSON log message, got %v", err)
+	}
+
+	if err := client.StopInstance(ctx, "test"); err != nil {
+		t.Fatalf("StopInstance: %v", err)
+	}
+
+	// Edits add to lists, --set expressions replace what they select
+	if err := client.EditInstance(ctx, "test", []string{"--mount=~/src:w", "--network=vzNAT", "--dns=1.1.1.1", "--video"}); err != nil {
+		t.Fatalf("EditInstance: %v", err)
+	}
+	set := `--set=.dns=[] | .hostResolver.enabled=true | del(.mounts[] | select(.location == "~/src")) | .mounts += [{"location":"~/src"}] | ` +
+		`del(.networks[] | select(.vzNAT == true)) | .video.display="none" | .additionalDisks=[]`
+	if err := client.EditInstance(ctx, "test", []string{set}); err != nil {
+		t.Fatalf("EditInstance: %v", err)
+	}
+
+	inst, err = client.GetInstance(ctx, "test")
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	mounts := inst.Config.Mounts
+	if len(mounts) != 3 || mounts[2].Location != "/Users/me/src" || mounts[2].Writable != nil {
+		t.Errorf("expected the template mounts and a read-only ~/src, got %+v", mounts)
+	}
+	if len(inst.Config.DNS) != 0 || len(inst.Config.Networks) != 0 || *inst.Config.Video.Display != "none" || len(inst.AdditionalDisks) != 0 {
+		t.Errorf("expected dns, networks, video and disks to be removed, got %+v", inst.Config)
+	}
+	if disk := fake.Disk("data"); disk.Instance != "" {
+		t.Errorf("expected disk to be detached, got %q", disk.Instance)
+	}
+
+	if err := client.DeleteInstance(ctx, "test"); err != nil {
+		t.Fatalf("DeleteInstance: %v", err)
+	}
+
+	if _, err := client.GetInstance(ctx, "test"); !errors.Is(err, limactl.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := client.ResizeDisk(ctx, "data", 5); err == nil {
+		t.Error("expected shrinking the disk to fail")
+	}
+
+	if err := client.DeleteDisk(ctx, "data"); err != nil {
+		t.Fatalf("DeleteDisk: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// localHost is the identity host of instances and disks on the machine that
+// runs Terraform.
+const localHost = "localhost"
+
+// LimaIdentityModel identifies an instance or disk across Lima stores.
+type LimaIdentityModel struct {
+	Name     types.String `tfsdk:"name"`
+	LimaHome types.String `tfsdk:"lima_home"`
+	Host     types.String `tfsdk:"host"`
+}
+
+// identitySchema returns the identity schema of a resource of the given kind,
+// "instance" or "disk".
+func identitySchema(kind string) identityschema.Schema {
+	return identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"name": identityschema.StringAttribute{
+				Description:       fmt.Sprintf("Name of the %s in Lima, including the `name_prefix` of the provider.", kind),
+				RequiredForImport: true,
+			},
+			"lima_home": identityschema.StringAttribute{
+				Description:       fmt.Sprintf("LIMA_HOME of the %s. Defaults to the Lima home of the provider when importing.", kind),
+				OptionalForImport: true,
+			},
+			"host": identityschema.StringAttribute{
+				Description:       fmt.Sprintf("Host of the Lima store, the ssh host of the provider or `%s`. Defaults to the host of the provider when importing.", localHost),
+				OptionalForImport: true,
+			},
+		},
+	}
+}
+
+// identity returns the identity of the instance or disk configured as name in
+// the store the provider is configured for.
+func (d *LimaProviderData) identity(name types.String) LimaIdentityModel {
+	if !name.IsNull() && !name.IsUnknown() {
+		name = types.StringValue(d.limaName(name.ValueString()))
+	}
+
+	return LimaIdentityModel{
+		Name:     name,
+		LimaHome: types.StringValue(d.Client.Home()),
+		Host:     types.StringValue(d.host),
+	}
+}
+
+// setIdentity stores the identity of the named instance or disk, when
+// Terraform supports resource identity.
+func (d *LimaProviderData) setIdentity(ctx context.Context, diags *diag.Diagnostics, identity *tfsdk.ResourceIdentity, name types.String) {
+	if identity == nil {
+		return
+	}
+
+	diags.Append(identity.Set(ctx, d.identity(name))...)
+}
+
+// checkIdentity reports an error when identity refers to a Lima store other
+// than the one the provider is configured for, for example after lima_home or
+// the ssh host was changed, or to another limactl name than the configured
+// name gets after name_prefix was changed. Operating on the configured store
+// would then affect a different object.
+func (d *LimaProviderData) checkIdentity(ctx context.Context, diags *diag.Diagnostics, identity *tfsdk.ResourceIdentity, kind string, name types.String) {
+	if identity == nil || identity.Raw.IsNull() {
+		return
+	}
+
+	var current LimaIdentityModel
+	diags.Append(identity.Get(ctx, &current)...)
+	if diags.HasError() {
+		return
+	}
+
+	configured := d.identity(name)
+	if !name.IsNull() && !current.Name.IsNull() && current.Name.ValueString() != configured.Name.ValueString() {
+		diags.AddError(
+			"Lima name prefix mismatch",
+			fmt.Sprintf("The %s %q in state is named %q in Lima, but the provider name_prefix %q names it %q. "+
+				"Restore the previous name_prefix, or remove the %s from state with `terraform state rm` and import it again.",
+				kind, name.ValueString(), current.Name.ValueString(), d.namePrefix, configured.Name.ValueString(), kind),
+		)
+		return
+	}
+
+	if identityMatches(current, configured) {
+		return
+	}
+
+	diags.AddError(
+		"Lima store mismatch",
+		fmt.Sprintf("The %s %q in state belongs to LIMA_HOME %q on %s, but the provider is configured for LIMA_HOME %q on %s. "+
+			"Configure the provider for the original store, or remove the %s from state with `terraform state rm` and import it again.",
+			kind, current.Name.ValueString(), current.LimaHome.ValueString(), current.Host.ValueString(),
+			configured.LimaHome.ValueString(), configured.Host.ValueString(), kind),
+	)
+}
+
+// identityMatches reports whether got refers to the same store as want. Unset
+// attributes match any store.
+func identityMatches(got, want LimaIdentityModel) bool {
+	if !got.LimaHome.IsNull() && got.LimaHome.ValueString() != want.LimaHome.ValueString() {
+		return false
+	}
+
+	if !got.Host.IsNull() && got.Host.ValueString() != want.Host.ValueString() {
+		return false
+	}
+
+	return true
+}
+
+// importState imports an instance or disk by name, or by identity from an
+// import block, which must refer to the store the provider is configured for.
+// The name_prefix of the provider is stripped from the imported name, and the
+// ownership marker found is reported.
+func (d *LimaProviderData) importState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse, kind string) {
+	resource.ImportStatePassthroughWithIdentity(ctx, path.Root("name"), path.Root("name"), req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var name types.String
+	resp.Diagnostics.Append(resp.State.GetAttribute(ctx, path.Root("name"), &name)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if configName, ok := d.configName(name.ValueString()); ok && configName != "" {
+		name = types.StringValue(configName)
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), name)...)
+
+	ctx = limactl.WithOperation(ctx, "lima_"+kind+"."+d.limaName(name.ValueString()), limactl.OperationImport)
+
+	// The identity may name the instance or disk with or without the prefix.
+	if req.ID == "" {
+		d.checkIdentity(ctx, &resp.Diagnostics, req.Identity, kind, types.StringNull())
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	d.setIdentity(ctx, &resp.Diagnostics, resp.Identity, name)
+	d.reportOwner(ctx, &resp.Diagnostics, kind, name.ValueString())
+}
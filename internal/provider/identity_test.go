@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func testIdentity(t *testing.T, name, limaHome, host any) *tfsdk.ResourceIdentity {
+	t.Helper()
+
+	s := identitySchema("instance")
+	objectType, ok := s.Type().TerraformType(context.Background()).(tftypes.Object)
+	if !ok {
+		t.Fatal("identity schema is not an object")
+	}
+
+	return &tfsdk.ResourceIdentity{
+		Schema: s,
+		Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+			"name":      tftypes.NewValue(tftypes.String, name),
+			"lima_home": tftypes.NewValue(tftypes.String, limaHome),
+			"host":      tftypes.NewValue(tftypes.String, host),
+		}),
+	}
+}
+
+func TestCheckIdentity(t *testing.T) {
+	ctx := context.Background()
+	d := &LimaProviderData{
+		Client: limactl.New(limactl.Config{LimaHome: "/home/me/.lima-ci"}),
+		host:   localHost,
+	}
+
+	tests := map[string]struct {
+		identity   *tfsdk.ResourceIdentity
+		namePrefix string
+		mismatch   bool
+	}{
+		"same store":       {identity: testIdentity(t, "dev", "/home/me/.lima-ci", localHost)},
+		"no identity":      {identity: nil},
+		"unset attributes": {identity: testIdentity(t, "dev", nil, nil)},
+		"other lima home":  {identity: testIdentity(t, "dev", "/home/me/.lima", localHost), mismatch: true},
+		"other host":       {identity: testIdentity(t, "dev", "/home/me/.lima-ci", "buildbox.internal"), mismatch: true},
+		"name prefix":      {identity: testIdentity(t, "alice-dev", "/home/me/.lima-ci", localHost), namePrefix: "alice-"},
+		"new name prefix":  {identity: testIdentity(t, "dev", "/home/me/.lima-ci", localHost), namePrefix: "alice-", mismatch: true},
+		"other name prefix": {
+			identity:   testIdentity(t, "alice-dev", "/home/me/.lima-ci", localHost),
+			namePrefix: "bob-",
+			mismatch:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			d.namePrefix = tt.namePrefix
+
+			var diags diag.Diagnostics
+			d.checkIdentity(ctx, &diags, tt.identity, "instance", types.StringValue("dev"))
+
+			if diags.HasError() != tt.mismatch {
+				t.Fatalf("expected mismatch %t, got %v", tt.mismatch, diags)
+			}
+
+			if tt.mismatch && !strings.Contains(diags[0].Detail(), `"dev"`) {
+				t.Errorf("expected the detail to name the instance, got %q", diags[0].Detail())
+			}
+		})
+	}
+}
+
+func TestSetIdentity(t *testing.T) {
+	ctx := context.Background()
+	d := &LimaProviderData{
+		Client: limactl.New(limactl.Config{LimaHome: "/home/me/.lima/"}),
+		host:   localHost,
+	}
+
+	identity := testIdentity(t, nil, nil, nil)
+
+	var diags diag.Diagnostics
+	d.setIdentity(ctx, &diags, identity, types.StringValue("dev"))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	var got LimaIdentityModel
+	diags.Append(identity.Get(ctx, &got)...)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	want := LimaIdentityModel{
+		Name:     types.StringValue("dev"),
+		LimaHome: types.StringValue("/home/me/.lima"),
+		Host:     types.StringValue(localHost),
+	}
+	if got != want {
+		t.Errorf("got identity %+v, want %+v", got, want)
+	}
+}
+
+func TestSSHHostIdentity(t *testing.T) {
+	tests := []struct {
+		port int64
+		want string
+	}{
+		{0, "buildbox.internal"},
+		{22, "buildbox.internal"},
+		{2222, "buildbox.internal:2222"},
+	}
+
+	for _, tt := range tests {
+		data := &LimaProviderSSHModel{Host: types.StringValue("buildbox.internal"), Port: types.Int64Value(tt.port)}
+		if tt.port == 0 {
+			data.Port = types.Int64Null()
+		}
+
+		if got := sshHostIdentity(data); got != tt.want {
+			t.Errorf("sshHostIdentity(port %d) = %q, want %q", tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestImportStateAuditOperation(t *testing.T) {
+	auditLogPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, map[string]tftypes.Value{
+		"audit_log_path": tftypes.NewValue(tftypes.String, auditLogPath),
+	})
+
+	h.create("lima_instance", h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "dev"),
+	}))
+	h.importResource("lima_instance", "dev")
+
+	data, err := os.ReadFile(auditLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var imports []limactl.AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry limactl.AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Operation == limactl.OperationImport {
+			imports = append(imports, entry)
+		}
+	}
+
+	if len(imports) == 0 || imports[0].Resource != "lima_instance.dev" {
+		t.Errorf("expected the import to be attributed to lima_instance.dev, got %+v", imports)
+	}
+}
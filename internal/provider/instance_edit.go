@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// setExpression returns the limactl edit --set expression that reconciles the
+// lima.yaml of the instance in state with plan, or "" when nothing changed.
+// limactl only takes one --set, so the changes are joined into one yq
+// pipeline. Unlike the create flags, which add to the template, every change
+// replaces what Terraform manages, so that removed list entries and options
+// turned off are applied too. Mounts and networks of the template are kept,
+// configured tells which ones Terraform managed before.
+func (r *LimaInstanceResource) setExpression(ctx context.Context, plan LimaInstanceResourceModel, state LimaInstanceResourceModel, configured configuredAttributes) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	var exprs []string
+
+	// Removed resources go back to those of the template; the flags set the
+	// others
+	for _, resource := range []struct {
+		field       string
+		plan, state attr.Value
+	}{
+		{"cpus", plan.Cpus, state.Cpus},
+		{"disk", plan.Disk, state.Disk},
+		{"memory", plan.Memory, state.Memory},
+		{"mountType", plan.MountType, state.MountType},
+	} {
+		if resource.plan.IsNull() && !resource.state.IsNull() {
+			exprs = append(exprs, "del(."+resource.field+")")
+		}
+	}
+
+	if !plan.DNS.Equal(state.DNS) {
+		dns, d := listStrings(ctx, plan.DNS)
+		diags.Append(d...)
+		old, d := configuredStrings(ctx, state.DNS, configured.has("dns"))
+		diags.Append(d...)
+
+		// Custom DNS servers replace the host resolver, which only comes
+		// back if Terraform turned it off
+		expr := ".dns=" + jsonValue(dns)
+		switch {
+		case len(dns) > 0:
+			expr += " | .hostResolver.enabled=false"
+		case len(old) > 0:
+			expr += " | .hostResolver.enabled=true"
+		}
+		exprs = append(exprs, expr)
+	}
+
+	// mount_writable applies to every mount, the template ones too
+	writableChanged := !plan.MountWritable.Equal(state.MountWritable)
+	if writableChanged {
+		exprs = append(exprs, fmt.Sprintf(".mounts[].writable=%t", plan.MountWritable.ValueBool()))
+	}
+
+	// The writable flags of configured mounts are then set again
+	if !plan.Mount.Equal(state.Mount) || writableChanged && configured.has("mount") {
+		old, d := configuredStrings(ctx, state.Mount, configured.has("mount"))
+		diags.Append(d...)
+		mounts, d := listStrings(ctx, plan.Mount)
+		diags.Append(d...)
+
+		exprs = append(exprs, mountsExpression(old, mounts, plan.MountWritable.ValueBool())...)
+	}
+
+	if !plan.Network.Equal(state.Network) {
+		old, d := configuredStrings(ctx, state.Network, configured.has("network"))
+		diags.Append(d...)
+		networks, d := listStrings(ctx, plan.Network)
+		diags.Append(d...)
+
+		exprs = append(exprs, networksExpression(old, networks)...)
+	}
+
+	if !plan.MountInotify.Equal(state.MountInotify) {
+		exprs = append(exprs, fmt.Sprintf(".mountInotify=%t", plan.MountInotify.ValueBool()))
+	}
+
+	if !plan.Rosetta.Equal(state.Rosetta) {
+		exprs = append(exprs, fmt.Sprintf(".rosetta.enabled=%t | .rosetta.binfmt=%t", plan.Rosetta.ValueBool(), plan.Rosetta.ValueBool()))
+	}
+
+	if !plan.Video.Equal(state.Video) {
+		display := "none"
+		if plan.Video.ValueBool() {
+			display = "default"
+		}
+		exprs = append(exprs, fmt.Sprintf(".video.display=%s", jsonValue(display)))
+	}
+
+	if !plan.Disks.Equal(state.Disks) {
+		expr, d := r.additionalDisksExpression(ctx, plan.Disks)
+		diags.Append(d...)
+		exprs = append(exprs, expr)
+	}
+
+	if diags.HasError() {
+		return "", diags
+	}
+
+	return strings.Join(exprs, " | "), diags
+}
+
+// mountsExpression replaces the mounts configured before, old, with mounts.
+// Lima keeps mount locations as written, so they are matched by location.
+// Mounts that are kept are updated in place, so that the mount point and
+// options of a template mount with the same location are kept.
+func mountsExpression(old []string, mounts []string, allWritable bool) []string {
+	var exprs []string
+
+	locations := map[bool][]string{}
+	entries := make([]limactl.Mount, 0, len(mounts))
+	for _, mount := range mounts {
+		location, writable := strings.CutSuffix(mount, ":w")
+		writable = writable || allWritable
+
+		locations[writable] = append(locations[writable], location)
+
+		entry := limactl.Mount{Location: location}
+		if writable {
+			entry.Writable = &writable
+		}
+		entries = append(entries, entry)
+	}
+
+	var removed []string
+	for _, mount := range old {
+		location, _ := strings.CutSuffix(mount, ":w")
+		if !slices.Contains(locations[true], location) && !slices.Contains(locations[false], location) && !slices.Contains(removed, location) {
+			removed = append(removed, location)
+		}
+	}
+	if len(removed) > 0 {
+		exprs = append(exprs, fmt.Sprintf("del(.mounts[] | select(%s))", locationConditions(removed)))
+	}
+
+	for _, writable := range []bool{false, true} {
+		if len(locations[writable]) > 0 {
+			exprs = append(exprs, fmt.Sprintf("(.mounts[] | select(%s)).writable=%t", locationConditions(locations[writable]), writable))
+		}
+	}
+
+	// New mounts are appended, the duplicates of kept ones dropped again
+	if len(entries) > 0 {
+		exprs = append(exprs, ".mounts += "+jsonValue(entries), ".mounts |= unique_by(.location)")
+	}
+
+	return exprs
+}
+
+// locationConditions returns the select conditions that match mounts at
+// locations.
+func locationConditions(locations []string) string {
+	conditions := make([]string, 0, len(locations))
+	for _, location := range locations {
+		conditions = append(conditions, ".location == "+jsonValue(location))
+	}
+
+	return strings.Join(conditions, " or ")
+}
+
+// networksExpression replaces the networks configured before, old, with
+// networks.
+func networksExpression(old []string, networks []string) []string {
+	var exprs []string
+
+	var conditions []string
+	for _, network := range slices.Concat(old, networks) {
+		condition := ".vzNAT == true"
+		if lima, ok := strings.CutPrefix(network, "lima:"); ok {
+			condition = ".lima == " + jsonValue(lima)
+		}
+		if !slices.Contains(conditions, condition) {
+			conditions = append(conditions, condition)
+		}
+	}
+	if len(conditions) > 0 {
+		exprs = append(exprs, fmt.Sprintf("del(.networks[] | select(%s))", strings.Join(conditions, " or ")))
+	}
+
+	if len(networks) > 0 {
+		entries := make([]limactl.Network, 0, len(networks))
+		for _, network := range networks {
+			var entry limactl.Network
+			if lima, ok := strings.CutPrefix(network, "lima:"); ok {
+				entry.Lima = lima
+			} else {
+				vzNAT := true
+				entry.VZNAT = &vzNAT
+			}
+			entries = append(entries, entry)
+		}
+
+		exprs = append(exprs, ".networks += "+jsonValue(entries))
+	}
+
+	return exprs
+}
+
+// additionalDisksExpression returns the --set expression that attaches disks,
+// with the provider name_prefix applied to their names.
+func (r *LimaInstanceResource) additionalDisksExpression(ctx context.Context, list types.List) (string, diag.Diagnostics) {
+	var disks []DisksModel
+	diags := list.ElementsAs(ctx, &disks, false)
+	if diags.HasError() {
+		return "", diags
+	}
+
+	diskArray := []limactl.AdditionalDisk{}
+	for _, disk := range disks {
+		diskArray = append(diskArray, limactl.AdditionalDisk{
+			Name:       r.providerData.limaName(disk.Name.ValueString()),
+			MountPoint: disk.MountPoint.ValueString(),
+		})
+	}
+
+	return ".additionalDisks=" + jsonValue(diskArray), diags
+}
+
+// listStrings returns the elements of a list of strings, none when it is
+// null.
+func listStrings(ctx context.Context, list types.List) ([]string, diag.Diagnostics) {
+	values := []string{}
+	if list.IsNull() || list.IsUnknown() {
+		return values, nil
+	}
+
+	diags := list.ElementsAs(ctx, &values, false)
+	return values, diags
+}
+
+// configuredStrings returns the elements of a list of strings in state if
+// Terraform configured it, and none when it holds what the template set.
+func configuredStrings(ctx context.Context, list types.List, configured bool) ([]string, diag.Diagnostics) {
+	if !configured {
+		return []string{}, nil
+	}
+
+	return listStrings(ctx, list)
+}
+
+// jsonValue returns v as JSON, which yq reads as a literal. It is only used
+// with strings and lists of strings or Lima config entries, which always
+// marshal.
+func jsonValue(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestSetExpression(t *testing.T) {
+	ctx := context.Background()
+
+	stringList := func(values ...string) types.List {
+		list, _ := types.ListValueFrom(ctx, types.StringType, append([]string{}, values...))
+		return list
+	}
+
+	model := func() LimaInstanceResourceModel {
+		return LimaInstanceResourceModel{
+			DNS:           types.ListNull(types.StringType),
+			Mount:         types.ListNull(types.StringType),
+			MountInotify:  types.BoolValue(false),
+			MountWritable: types.BoolValue(false),
+			Network:       types.ListNull(types.StringType),
+			Rosetta:       types.BoolValue(false),
+			Video:         types.BoolValue(false),
+			Disks:         types.ListValueMust(disksObjectType, nil),
+		}
+	}
+
+	tests := map[string]struct {
+		state, plan func(*LimaInstanceResourceModel)
+		configured  configuredAttributes
+		want        string
+	}{
+		"unchanged": {
+			state: func(m *LimaInstanceResourceModel) { m.DNS = stringList("1.1.1.1") },
+			plan:  func(m *LimaInstanceResourceModel) { m.DNS = stringList("1.1.1.1") },
+		},
+		"dns": {
+			plan: func(m *LimaInstanceResourceModel) { m.DNS = stringList("1.1.1.1", "8.8.8.8") },
+			want: `.dns=["1.1.1.1","8.8.8.8"] | .hostResolver.enabled=false`,
+		},
+		"dns removed": {
+			state: func(m *LimaInstanceResourceModel) { m.DNS = stringList("1.1.1.1") },
+			want:  `.dns=[] | .hostResolver.enabled=true`,
+		},
+		"template dns removed": {
+			state:      func(m *LimaInstanceResourceModel) { m.DNS = stringList("1.1.1.1") },
+			configured: configuredAttributes{},
+			want:       `.dns=[]`,
+		},
+		"mounts": {
+			state: func(m *LimaInstanceResourceModel) { m.Mount = stringList("~/src", "/data:w") },
+			plan:  func(m *LimaInstanceResourceModel) { m.Mount = stringList("/data", "/cache:w") },
+			want: `del(.mounts[] | select(.location == "~/src")) | (.mounts[] | select(.location == "/data")).writable=false | ` +
+				`(.mounts[] | select(.location == "/cache")).writable=true | .mounts += [{"location":"/data"},{"location":"/cache","writable":true}] | ` +
+				`.mounts |= unique_by(.location)`,
+		},
+		"mounts removed": {
+			state: func(m *LimaInstanceResourceModel) { m.Mount = stringList("/data") },
+			want:  `del(.mounts[] | select(.location == "/data"))`,
+		},
+		"mount writable off": {
+			state: func(m *LimaInstanceResourceModel) {
+				m.MountWritable = types.BoolValue(true)
+				m.Mount = stringList("/data")
+			},
+			plan: func(m *LimaInstanceResourceModel) { m.Mount = stringList("/data") },
+			want: `.mounts[].writable=false | (.mounts[] | select(.location == "/data")).writable=false | .mounts += [{"location":"/data"}] | .mounts |= unique_by(.location)`,
+		},
+		"mount writable off without mounts": {
+			state:      func(m *LimaInstanceResourceModel) { m.MountWritable = types.BoolValue(true) },
+			configured: configuredAttributes{},
+			want:       `.mounts[].writable=false`,
+		},
+		"mount writable off with template mounts": {
+			state: func(m *LimaInstanceResourceModel) {
+				m.MountWritable = types.BoolValue(true)
+				m.Mount = stringList("/Users/me:w", "/tmp/lima:w")
+			},
+			plan:       func(m *LimaInstanceResourceModel) { m.Mount = stringList("/Users/me:w", "/tmp/lima:w") },
+			configured: configuredAttributes{"mount_writable": true},
+			want:       `.mounts[].writable=false`,
+		},
+		"mount writable on": {
+			plan: func(m *LimaInstanceResourceModel) {
+				m.MountWritable = types.BoolValue(true)
+				m.Mount = stringList("/data")
+			},
+			state: func(m *LimaInstanceResourceModel) { m.Mount = stringList("/data") },
+			want:  `.mounts[].writable=true | (.mounts[] | select(.location == "/data")).writable=true | .mounts += [{"location":"/data","writable":true}] | .mounts |= unique_by(.location)`,
+		},
+		"template mounts": {
+			state:      func(m *LimaInstanceResourceModel) { m.Mount = stringList("/Users/me", "/tmp/lima:w") },
+			plan:       func(m *LimaInstanceResourceModel) { m.Mount = stringList("/data") },
+			configured: configuredAttributes{},
+			want:       `(.mounts[] | select(.location == "/data")).writable=false | .mounts += [{"location":"/data"}] | .mounts |= unique_by(.location)`,
+		},
+		"template networks": {
+			state:      func(m *LimaInstanceResourceModel) { m.Network = stringList("lima:shared") },
+			plan:       func(m *LimaInstanceResourceModel) { m.Network = stringList("vzNAT") },
+			configured: configuredAttributes{"mount": true},
+			want:       `del(.networks[] | select(.vzNAT == true)) | .networks += [{"vzNAT":true}]`,
+		},
+		"resources removed": {
+			state: func(m *LimaInstanceResourceModel) {
+				m.Cpus = types.Int64Value(2)
+				m.Memory = types.Float64Value(4)
+				m.MountType = types.StringValue("9p")
+			},
+			plan: func(m *LimaInstanceResourceModel) { m.Disk = types.Float64Value(50) },
+			want: `del(.cpus) | del(.memory) | del(.mountType)`,
+		},
+		"networks": {
+			state: func(m *LimaInstanceResourceModel) { m.Network = stringList("vzNAT", "lima:shared") },
+			plan:  func(m *LimaInstanceResourceModel) { m.Network = stringList("lima:bridged") },
+			want:  `del(.networks[] | select(.vzNAT == true or .lima == "shared" or .lima == "bridged")) | .networks += [{"lima":"bridged"}]`,
+		},
+		"options off": {
+			state: func(m *LimaInstanceResourceModel) {
+				m.MountInotify = types.BoolValue(true)
+				m.Rosetta = types.BoolValue(true)
+				m.Video = types.BoolValue(true)
+			},
+			want: `.mountInotify=false | .rosetta.enabled=false | .rosetta.binfmt=false | .video.display="none"`,
+		},
+		"video on": {
+			plan: func(m *LimaInstanceResourceModel) { m.Video = types.BoolValue(true) },
+			want: `.video.display="default"`,
+		},
+		"disks": {
+			plan: func(m *LimaInstanceResourceModel) {
+				m.Disks = types.ListValueMust(disksObjectType, []attr.Value{
+					types.ObjectValueMust(disksObjectType.AttrTypes, map[string]attr.Value{
+						"name":        types.StringValue("data"),
+						"mount_point": types.StringValue("/mnt/data"),
+					}),
+				})
+			},
+			want: `.additionalDisks=[{"name":"alice-data","mountPoint":"/mnt/data"}]`,
+		},
+	}
+
+	r := &LimaInstanceResource{providerData: &LimaProviderData{namePrefix: "alice-"}}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			state, plan := model(), model()
+			if tt.state != nil {
+				tt.state(&state)
+			}
+			if tt.plan != nil {
+				tt.plan(&plan)
+			}
+
+			got, diags := r.setExpression(ctx, plan, state, tt.configured)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// disksObjectType is the element type of the disks block.
+var disksObjectType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"name":        types.StringType,
+	"mount_point": types.StringType,
+}}
+
+// timeoutsObjectType is the type of the timeouts blocks.
+var timeoutsObjectType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"create": types.StringType,
+	"read":   types.StringType,
+	"update": types.StringType,
+	"delete": types.StringType,
+}}
+
+// instanceModelFromLima describes an existing instance as a lima_instance
+// resource, with namePrefix stripped from its name and disk references. The
+// template an instance was created from is not recorded by Lima
+// and is left null, as are the create-time switches such as mount_none whose
+// effect cannot be told apart from the resulting configuration.
+func instanceModelFromLima(inst *limactl.Instance, namePrefix string) (LimaInstanceResourceModel, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	cfg := inst.Config
+	if cfg == nil {
+		cfg = &limactl.InstanceConfig{}
+	}
+
+	name := strings.TrimPrefix(inst.Name, namePrefix)
+
+	data := LimaInstanceResourceModel{
+		Name:          types.StringValue(name),
+		Id:            types.StringValue(name),
+		Template:      types.StringNull(),
+		Arch:          stringOrNull(inst.Arch),
+		VmType:        stringOrNull(inst.VMType),
+		Cpus:          types.Int64Value(int64(inst.CPUs)),
+		Memory:        types.Float64Value(limactl.BytesToGiB(inst.Memory)),
+		Disk:          types.Float64Value(limactl.BytesToGiB(inst.Disk)),
+		Containerd:    containerdMode(cfg.Containerd),
+		MountInotify:  types.BoolValue(boolValue(cfg.MountInotify)),
+		MountNone:     types.BoolValue(false),
+		MountType:     types.StringPointerValue(cfg.MountType),
+		MountWritable: types.BoolValue(false),
+		Plain:         types.BoolValue(boolValue(cfg.Plain)),
+		Rosetta:       types.BoolValue(boolValue(cfg.Rosetta.Enabled)),
+		Video:         types.BoolValue(cfg.Video.Display != nil && *cfg.Video.Display != "" && *cfg.Video.Display != "none"),
+		Running:       types.BoolValue(inst.Status == limactl.StatusRunning),
+		ForceDelete:   types.BoolValue(false),
+		Timeouts:      timeouts.Value{Object: types.ObjectNull(timeoutsObjectType.AttrTypes)},
+	}
+
+	var mounts []string
+	for _, m := range cfg.Mounts {
+		mount := m.Location
+		if boolValue(m.Writable) {
+			mount += ":w"
+		}
+		mounts = append(mounts, mount)
+	}
+
+	var networks []string
+	for _, n := range cfg.Networks {
+		switch {
+		case n.Lima != "":
+			networks = append(networks, "lima:"+n.Lima)
+		case boolValue(n.VZNAT):
+			networks = append(networks, "vzNAT")
+		}
+	}
+
+	var d diag.Diagnostics
+	data.DNS, d = stringListOrNull(cfg.DNS)
+	diags.Append(d...)
+	data.Mount, d = stringListOrNull(mounts)
+	diags.Append(d...)
+	data.Network, d = stringListOrNull(networks)
+	diags.Append(d...)
+
+	disks := make([]attr.Value, 0, len(inst.AdditionalDisks))
+	for _, disk := range inst.AdditionalDisks {
+		obj, d := types.ObjectValue(disksObjectType.AttrTypes, map[string]attr.Value{
+			"name":        types.StringValue(strings.TrimPrefix(disk.Name, namePrefix)),
+			"mount_point": types.StringValue(disk.MountPoint),
+		})
+		diags.Append(d...)
+		disks = append(disks, obj)
+	}
+
+	data.Disks, d = types.ListValue(disksObjectType, disks)
+	diags.Append(d...)
+
+	return data, diags
+}
+
+// refreshInstanceModel updates data, the state of an instance, with its actual
+// configuration so that changes made outside of Terraform, such as
+// `limactl edit --cpus`, show up as a diff. Attributes that are null, because
+// the instance was imported or they were left to the template, take the
+// actual value. Values that Lima only represents differently keep the
+// configured spelling.
+func refreshInstanceModel(ctx context.Context, data *LimaInstanceResourceModel, inst *limactl.Instance, namePrefix string) diag.Diagnostics {
+	actual, diags := instanceModelFromLima(inst, namePrefix)
+	if diags.HasError() {
+		return diags
+	}
+
+	cfg := inst.Config
+	if cfg == nil {
+		cfg = &limactl.InstanceConfig{}
+	}
+
+	data.Cpus = actual.Cpus
+	data.Arch = actual.Arch
+	data.VmType = actual.VmType
+	data.Containerd = actual.Containerd
+	data.MountType = actual.MountType
+	data.MountInotify = actual.MountInotify
+	data.Plain = actual.Plain
+	data.Video = actual.Video
+	data.Rosetta = actual.Rosetta
+	data.Running = actual.Running
+
+	// The effect of these create-time switches cannot be read back
+	if data.MountNone.IsNull() {
+		data.MountNone = actual.MountNone
+	}
+	if data.MountWritable.IsNull() {
+		data.MountWritable = actual.MountWritable
+	}
+
+	data.Memory = refreshGiB(data.Memory, inst.Memory)
+	data.Disk = refreshGiB(data.Disk, inst.Disk)
+	data.DNS = refreshList(data.DNS, actual.DNS)
+	data.Disks = refreshList(data.Disks, actual.Disks)
+
+	var d diag.Diagnostics
+	data.Mount, d = refreshMounts(ctx, data.Mount, actual.Mount, cfg.Mounts, data.MountWritable.ValueBool())
+	diags.Append(d...)
+	data.Network, d = refreshNetworks(ctx, data.Network, actual.Network)
+	diags.Append(d...)
+
+	return diags
+}
+
+// refreshGiB returns the size in GiB of b bytes, or prior when it is within a
+// MiB of it, so that rounding is not reported as a change.
+func refreshGiB(prior types.Float64, b int64) types.Float64 {
+	if !prior.IsNull() && math.Abs(float64(limactl.GiBToBytes(prior.ValueFloat64())-b)) < 1<<20 {
+		return prior
+	}
+
+	return types.Float64Value(limactl.BytesToGiB(b))
+}
+
+// refreshList returns actual, or prior when both are empty, so that an empty
+// list is not reported as a change to null.
+func refreshList(prior types.List, actual types.List) types.List {
+	if !prior.IsNull() && len(prior.Elements()) == 0 && len(actual.Elements()) == 0 {
+		return prior
+	}
+
+	return actual
+}
+
+// refreshMounts returns the configured mounts that the instance still has, or
+// all of them, actual, when none are configured. Lima adds --mount to the
+// mounts of the template and expands ~, so mounts that are not configured are
+// ignored and configured ones keep their spelling; a removed mount or a
+// changed writable flag is reported. With mount_writable every mount is
+// writable and the flag is not compared.
+func refreshMounts(ctx context.Context, prior types.List, actual types.List, mounts []limactl.Mount, allWritable bool) (types.List, diag.Diagnostics) {
+	if prior.IsNull() {
+		return actual, nil
+	}
+
+	var configured []string
+	diags := prior.ElementsAs(ctx, &configured, false)
+	if diags.HasError() {
+		return prior, diags
+	}
+
+	values := []string{}
+	for _, mount := range configured {
+		location, writable := strings.CutSuffix(mount, ":w")
+
+		for _, m := range mounts {
+			if !sameLocation(location, m.Location) {
+				continue
+			}
+
+			if !allWritable {
+				writable = boolValue(m.Writable)
+			}
+			if writable {
+				location += ":w"
+			}
+			values = append(values, location)
+			break
+		}
+	}
+
+	list, d := types.ListValueFrom(ctx, types.StringType, values)
+	diags.Append(d...)
+
+	return list, diags
+}
+
+// refreshNetworks returns the configured networks that the instance still
+// has, or all of them, actual, when none are configured. Lima adds --network
+// to the networks of the template, so networks that are not configured are
+// ignored.
+func refreshNetworks(ctx context.Context, prior types.List, actual types.List) (types.List, diag.Diagnostics) {
+	if prior.IsNull() {
+		return actual, nil
+	}
+
+	var configured, networks []string
+	diags := prior.ElementsAs(ctx, &configured, false)
+	diags.Append(actual.ElementsAs(ctx, &networks, false)...)
+	if diags.HasError() {
+		return prior, diags
+	}
+
+	values := []string{}
+	for _, network := range configured {
+		for _, n := range networks {
+			if n == network {
+				values = append(values, network)
+				break
+			}
+		}
+	}
+
+	list, d := types.ListValueFrom(ctx, types.StringType, values)
+	diags.Append(d...)
+
+	return list, diags
+}
+
+// sameLocation reports whether the configured mount location refers to the
+// location reported by Lima, which has ~ expanded to the home directory.
+func sameLocation(configured string, actual string) bool {
+	configured, actual = path.Clean(configured), path.Clean(actual)
+	if configured == actual {
+		return true
+	}
+
+	rest, ok := strings.CutPrefix(configured, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return false
+	}
+
+	home, ok := strings.CutSuffix(actual, rest)
+	return ok && isHomeDir(home)
+}
+
+// isHomeDir reports whether dir looks like the home directory of a user on
+// macOS or Linux.
+func isHomeDir(dir string) bool {
+	switch path.Dir(dir) {
+	case "/Users", "/home":
+		return true
+	}
+
+	return dir == "/root" || dir == "/var/root"
+}
+
+// containerdMode returns the containerd attribute for the containerd section
+// of lima.yaml, or null when it was left to the template.
+func containerdMode(c limactl.Containerd) types.String {
+	if c.System == nil && c.User == nil {
+		return types.StringNull()
+	}
+
+	system, user := boolValue(c.System), boolValue(c.User)
+	switch {
+	case system && user:
+		return types.StringValue("user+system")
+	case system:
+		return types.StringValue("system")
+	case user:
+		return types.StringValue("user")
+	}
+
+	return types.StringValue("none")
+}
+
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+
+	return types.StringValue(s)
+}
+
+func stringListOrNull(values []string) (types.List, diag.Diagnostics) {
+	if len(values) == 0 {
+		return types.ListNull(types.StringType), nil
+	}
+
+	elements := make([]attr.Value, 0, len(values))
+	for _, v := range values {
+		elements = append(elements, types.StringValue(v))
+	}
+
+	return types.ListValue(types.StringType, elements)
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
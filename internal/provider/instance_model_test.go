@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func TestRefreshInstanceModel(t *testing.T) {
+	ctx := context.Background()
+	yes, no := true, false
+	display, mountType := "default", "virtiofs"
+
+	stringList := func(values ...string) types.List {
+		list, _ := types.ListValueFrom(ctx, types.StringType, append([]string{}, values...))
+		return list
+	}
+
+	prior := func() LimaInstanceResourceModel {
+		return LimaInstanceResourceModel{
+			Name:          types.StringValue("dev"),
+			Arch:          types.StringValue("aarch64"),
+			VmType:        types.StringValue("vz"),
+			Cpus:          types.Int64Value(2),
+			Memory:        types.Float64Value(1.3),
+			Disk:          types.Float64Value(100),
+			DNS:           stringList(),
+			Mount:         stringList("~/src", "/data:w"),
+			MountType:     types.StringValue("virtiofs"),
+			MountWritable: types.BoolValue(false),
+			Network:       stringList("vzNAT"),
+			Rosetta:       types.BoolValue(false),
+			Video:         types.BoolValue(false),
+			Disks:         types.ListValueMust(disksObjectType, nil),
+			Running:       types.BoolValue(true),
+		}
+	}
+
+	instance := func() *limactl.Instance {
+		return &limactl.Instance{
+			Name: "dev", Status: limactl.StatusRunning, VMType: "vz", Arch: "aarch64",
+			CPUs: 2, Memory: limactl.GiBToBytes(1.3) + 4096, Disk: 100 << 30,
+			Config: &limactl.InstanceConfig{
+				MountType: &mountType,
+				Mounts: []limactl.Mount{
+					{Location: "/Users/me"},
+					{Location: "/tmp/lima", Writable: &yes},
+					{Location: "/Users/me/src"},
+					{Location: "/data", Writable: &yes},
+				},
+				Networks: []limactl.Network{{Lima: "shared"}, {VZNAT: &yes}},
+			},
+		}
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		data := prior()
+		if diags := refreshInstanceModel(ctx, &data, instance(), ""); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+
+		want := prior()
+		for attribute, equal := range map[string]bool{
+			"arch":      data.Arch.Equal(want.Arch),
+			"cpus":      data.Cpus.Equal(want.Cpus),
+			"memory":    data.Memory.Equal(want.Memory),
+			"disk":      data.Disk.Equal(want.Disk),
+			"dns":       data.DNS.Equal(want.DNS),
+			"mount":     data.Mount.Equal(want.Mount),
+			"mountType": data.MountType.Equal(want.MountType),
+			"network":   data.Network.Equal(want.Network),
+			"disks":     data.Disks.Equal(want.Disks),
+		} {
+			if !equal {
+				t.Errorf("expected %s to be unchanged, got %+v", attribute, data)
+			}
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		inst := instance()
+		inst.Status = limactl.StatusStopped
+		inst.VMType = "qemu"
+		inst.CPUs = 8
+		inst.Memory = 8 << 30
+		inst.Config.Mounts = []limactl.Mount{{Location: "/Users/me/src", Writable: &yes}, {Location: "/data", Writable: &no}}
+		inst.Config.Networks = nil
+		inst.Config.DNS = []string{"1.1.1.1"}
+		inst.Config.Video.Display = &display
+		inst.AdditionalDisks = []limactl.AdditionalDisk{{Name: "alice-data", MountPoint: "/mnt/data"}}
+
+		data := prior()
+		if diags := refreshInstanceModel(ctx, &data, inst, "alice-"); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+
+		if data.VmType.ValueString() != "qemu" || data.Cpus.ValueInt64() != 8 || data.Memory.ValueFloat64() != 8 {
+			t.Errorf("expected qemu with 8 CPUs and 8GiB, got %v, %v and %v", data.VmType, data.Cpus, data.Memory)
+		}
+		if !data.Mount.Equal(stringList("~/src:w", "/data")) {
+			t.Errorf("expected the writable flags to be refreshed, got %v", data.Mount)
+		}
+		if !data.Network.Equal(stringList()) {
+			t.Errorf("expected the removed network to be reported, got %v", data.Network)
+		}
+		if !data.DNS.Equal(stringList("1.1.1.1")) {
+			t.Errorf("expected the added dns server, got %v", data.DNS)
+		}
+		if !data.Video.ValueBool() || data.Running.ValueBool() {
+			t.Errorf("expected video on a stopped instance, got %v and %v", data.Video, data.Running)
+		}
+
+		var disks []DisksModel
+		data.Disks.ElementsAs(ctx, &disks, false)
+		if len(disks) != 1 || disks[0].Name.ValueString() != "data" {
+			t.Errorf("expected the attached disk without the prefix, got %v", data.Disks)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		inst := instance()
+		inst.Config.Containerd = limactl.Containerd{System: &no, User: &yes}
+		inst.Config.Plain = &no
+		inst.Config.MountInotify = &yes
+
+		data := LimaInstanceResourceModel{
+			Name:          types.StringValue("dev"),
+			Cpus:          types.Int64Null(),
+			Memory:        types.Float64Null(),
+			Disk:          types.Float64Null(),
+			DNS:           types.ListNull(types.StringType),
+			Mount:         types.ListNull(types.StringType),
+			Network:       types.ListNull(types.StringType),
+			Disks:         types.ListNull(disksObjectType),
+			MountNone:     types.BoolNull(),
+			MountWritable: types.BoolNull(),
+		}
+		if diags := refreshInstanceModel(ctx, &data, inst, ""); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+
+		if data.Cpus.ValueInt64() != 2 || data.Disk.ValueFloat64() != 100 || data.Arch.ValueString() != "aarch64" {
+			t.Errorf("expected the actual resources, got %v, %v and %v", data.Cpus, data.Disk, data.Arch)
+		}
+		if data.Containerd.ValueString() != "user" || data.Plain.IsNull() || !data.MountInotify.ValueBool() {
+			t.Errorf("expected the actual options, got %v, %v and %v", data.Containerd, data.Plain, data.MountInotify)
+		}
+		if data.MountNone.ValueBool() || data.MountWritable.ValueBool() || data.MountNone.IsNull() || data.MountWritable.IsNull() {
+			t.Errorf("expected the create switches to be off, got %v and %v", data.MountNone, data.MountWritable)
+		}
+		if !data.Mount.Equal(stringList("/Users/me", "/tmp/lima:w", "/Users/me/src", "/data:w")) {
+			t.Errorf("expected every mount, got %v", data.Mount)
+		}
+		if !data.Network.Equal(stringList("lima:shared", "vzNAT")) {
+			t.Errorf("expected every network, got %v", data.Network)
+		}
+	})
+
+	t.Run("mount writable", func(t *testing.T) {
+		data := prior()
+		data.MountWritable = types.BoolValue(true)
+		data.Mount = stringList("~/src")
+
+		if diags := refreshInstanceModel(ctx, &data, instance(), ""); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+
+		if !data.Mount.Equal(stringList("~/src")) {
+			t.Errorf("expected the writable flag not to be compared, got %v", data.Mount)
+		}
+	})
+}
+
+func TestSameLocation(t *testing.T) {
+	tests := []struct {
+		configured, actual string
+		want               bool
+	}{
+		{"/data", "/data", true},
+		{"/data/", "/data", true},
+		{"/data", "/data2", false},
+		{"~", "/Users/me", true},
+		{"~/src", "/home/me/src", true},
+		{"~/src", "/root/src", true},
+		{"~/src", "/tmp/src", false},
+		{"~", "/tmp/lima", false},
+		{"~src", "/Users/me/src", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameLocation(tt.configured, tt.actual); got != tt.want {
+			t.Errorf("sameLocation(%q, %q) = %t, want %t", tt.configured, tt.actual, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// cleanupTimeout bounds the limactl commands that clean up after an
+// interrupted operation.
+const cleanupTimeout = time.Minute
+
+// interrupted reports whether err is the result of Terraform cancelling ctx.
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
+
+// cleanupContext returns a context that outlives the cancelled ctx, so that
+// cleanup commands still run.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// cleanupInterruptedCreate deletes whatever an interrupted or timed out create
+// left behind and reports what happened. exists looks the object up and
+// remove deletes it.
+func cleanupInterruptedCreate(ctx context.Context, diags *diag.Diagnostics, kind string, name string, recovery string, createErr error, exists func(context.Context) error, remove func(context.Context) error) {
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+
+	reason := "interrupted"
+	if timedOut(createErr) {
+		reason = "timed out"
+	}
+
+	summary := fmt.Sprintf("Lima %s creation %s", kind, reason)
+
+	err := exists(ctx)
+	if errors.Is(err, limactl.ErrNotFound) {
+		diags.AddError(summary, fmt.Sprintf("Creating %s %q %s before anything was created. Nothing was saved in state.\n\n%s", kind, name, reason, createErr))
+		return
+	}
+
+	if err == nil {
+		tflog.Warn(ctx, "Deleting "+reason+" Lima "+kind, map[string]any{
+			"name": name,
+		})
+
+		err = remove(ctx)
+	}
+
+	if err != nil {
+		diags.AddError(summary, fmt.Sprintf("Creating %s %q %s and what was created could not be removed: %s\n\nRun `%s` before applying again.\n\n%s", kind, name, reason, err, recovery, createErr))
+		return
+	}
+
+	diags.AddError(summary, fmt.Sprintf("Creating %s %q %s. The partially created %s was deleted and nothing was saved in state.\n\n%s", kind, name, reason, kind, createErr))
+}
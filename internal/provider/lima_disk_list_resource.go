@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/list/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+var _ list.ListResource = &LimaDiskListResource{}
+var _ list.ListResourceWithConfigure = &LimaDiskListResource{}
+
+func NewLimaDiskListResource() list.ListResource {
+	return &LimaDiskListResource{}
+}
+
+// LimaDiskListResource lists the disks in LIMA_HOME.
+type LimaDiskListResource struct {
+	providerData *LimaProviderData
+}
+
+type LimaDiskListModel struct {
+	NamePattern types.String `tfsdk:"name_pattern"`
+	Instance    types.String `tfsdk:"instance"`
+}
+
+func (r *LimaDiskListResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_disk"
+}
+
+func (r *LimaDiskListResource) ListResourceConfigSchema(ctx context.Context, req list.ListResourceSchemaRequest, resp *list.ListResourceSchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists Lima disks, including the ones not managed by Terraform. All filters are optional and combined. With the provider `name_prefix`, only the disks named with it are listed, and names are matched and returned without it.",
+		Attributes: map[string]schema.Attribute{
+			"name_pattern": schema.StringAttribute{
+				MarkdownDescription: "Shell pattern the disk name must match, such as `data-*`.",
+				Optional:            true,
+			},
+			"instance": schema.StringAttribute{
+				MarkdownDescription: "Only list disks attached to this instance. Set to `\"\"` to list detached disks.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *LimaDiskListResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*LimaProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected List Resource Configure Type",
+			fmt.Sprintf("Expected *LimaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *LimaDiskListResource) List(ctx context.Context, req list.ListRequest, stream *list.ListResultsStream) {
+	var filter LimaDiskListModel
+
+	diags := req.Config.Get(ctx, &filter)
+	if diags.HasError() {
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	if _, err := filepath.Match(filter.NamePattern.ValueString(), ""); err != nil {
+		diags.AddAttributeError(
+			path.Root("name_pattern"),
+			"Invalid name_pattern",
+			fmt.Sprintf("%q is not a valid pattern: %s", filter.NamePattern.ValueString(), err),
+		)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	ctx = limactl.WithOperation(ctx, "lima_disk.*", limactl.OperationRead)
+
+	disks, err := r.providerData.Client.ListDisks(ctx)
+	if err != nil {
+		addLimactlError(&diags, "Failed to list Lima disks", err, nil)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	stream.Results = func(push func(list.ListResult) bool) {
+		var count int64
+
+		for i := range disks {
+			disk := &disks[i]
+
+			// Disks outside the name_prefix namespace belong to others.
+			name, ok := r.providerData.configName(disk.Name)
+			if !ok || name == "" || !filter.matches(name, disk, r.providerData) {
+				continue
+			}
+
+			if req.Limit > 0 && count >= req.Limit {
+				return
+			}
+			count++
+
+			result := req.NewListResult(ctx)
+			result.DisplayName = fmt.Sprintf("%s (%gGiB)", name, limactl.BytesToGiB(disk.Size))
+			if disk.Instance != "" {
+				instance := strings.TrimPrefix(disk.Instance, r.providerData.namePrefix)
+				result.DisplayName = fmt.Sprintf("%s (%gGiB, attached to %s)", name, limactl.BytesToGiB(disk.Size), instance)
+			}
+
+			r.providerData.setIdentity(ctx, &result.Diagnostics, result.Identity, types.StringValue(name))
+
+			if req.IncludeResource {
+				data := diskModelFromLima(name, disk)
+				result.Diagnostics.Append(result.Resource.Set(ctx, &data)...)
+			}
+
+			if !push(result) {
+				return
+			}
+		}
+	}
+}
+
+// matches reports whether disk, configured as name, passes the filters.
+func (m LimaDiskListModel) matches(name string, disk *limactl.Disk, d *LimaProviderData) bool {
+	if !m.NamePattern.IsNull() {
+		if ok, _ := filepath.Match(m.NamePattern.ValueString(), name); !ok {
+			return false
+		}
+	}
+
+	if !m.Instance.IsNull() {
+		instance := m.Instance.ValueString()
+		if instance != "" {
+			instance = d.limaName(instance)
+		}
+
+		if instance != disk.Instance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// diskModelFromLima describes an existing disk, configured as name, as a
+// lima_disk resource.
+func diskModelFromLima(name string, disk *limactl.Disk) LimaDiskResourceModel {
+	return LimaDiskResourceModel{
+		Name:        types.StringValue(name),
+		Size:        types.Float64Value(limactl.BytesToGiB(disk.Size)),
+		ForceDelete: types.BoolValue(false),
+		Id:          types.StringValue(name),
+		Timeouts:    timeouts.Value{Object: types.ObjectNull(timeoutsObjectType.AttrTypes)},
+	}
+}
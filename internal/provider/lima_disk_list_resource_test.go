@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func TestLimaDiskListResource(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeLimactl()
+	fake.disks["data-1"] = &limactl.Disk{Name: "data-1", Size: 10 << 30, Format: "qcow2", Instance: "dev"}
+	fake.disks["data-2"] = &limactl.Disk{Name: "data-2", Size: 20 << 30, Format: "qcow2"}
+	fake.disks["cache"] = &limactl.Disk{Name: "cache", Size: 5 << 30, Format: "raw", Instance: "ci"}
+
+	tests := map[string]struct {
+		filters map[string]string
+		names   []string
+	}{
+		"all":          {names: []string{"cache", "data-1", "data-2"}},
+		"name pattern": {filters: map[string]string{"name_pattern": "data-?"}, names: []string{"data-1", "data-2"}},
+		"instance":     {filters: map[string]string{"instance": "ci"}, names: []string{"cache"}},
+		"detached":     {filters: map[string]string{"instance": ""}, names: []string{"data-2"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			results := testListResults(t, fake, "", NewLimaDiskListResource(), &LimaDiskResource{}, tt.filters, true)
+
+			if len(results) != len(tt.names) {
+				t.Fatalf("expected %v, got %d results", tt.names, len(results))
+			}
+
+			for i, result := range results {
+				if result.Diagnostics.HasError() {
+					t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+				}
+
+				var data LimaDiskResourceModel
+				if diags := result.Resource.Get(ctx, &data); diags.HasError() {
+					t.Fatalf("unexpected diagnostics: %v", diags)
+				}
+
+				if data.Name.ValueString() != tt.names[i] || data.Id.ValueString() != tt.names[i] {
+					t.Errorf("expected disk %q, got %+v", tt.names[i], data)
+				}
+
+				if want := limactl.BytesToGiB(fake.disks[tt.names[i]].Size); data.Size.ValueFloat64() != want {
+					t.Errorf("expected size %g, got %v", want, data.Size)
+				}
+			}
+		})
+	}
+}
@@ -2,33 +2,39 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
-	"strings"
 
-	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
 )
 
 var _ resource.Resource = &LimaDiskResource{}
 var _ resource.ResourceWithImportState = &LimaDiskResource{}
+var _ resource.ResourceWithIdentity = &LimaDiskResource{}
 
 func NewLimaDiskResource() resource.Resource {
 	return &LimaDiskResource{}
 }
 
-type LimaDiskResource struct{}
+type LimaDiskResource struct {
+	providerData *LimaProviderData
+}
 
 type LimaDiskResourceModel struct {
-	Name types.String  `tfsdk:"name"`
-	Size types.Float64 `tfsdk:"size"`
-	Id   types.String  `tfsdk:"id"`
+	Name        types.String   `tfsdk:"name"`
+	Size        types.Float64  `tfsdk:"size"`
+	ForceDelete types.Bool     `tfsdk:"force_delete"`
+	Id          types.String   `tfsdk:"id"`
+	Timeouts    timeouts.Value `tfsdk:"timeouts"`
 }
 
 func (r *LimaDiskResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -50,6 +56,12 @@ func (r *LimaDiskResource) Schema(ctx context.Context, req resource.SchemaReques
 				MarkdownDescription: "Size of the disk in GiB. Can be increased (but not decreased) after creation.",
 				Required:            true,
 			},
+			"force_delete": schema.BoolAttribute{
+				MarkdownDescription: "Delete the disk even if it was not created by this workspace, for example after importing it. Must be applied before destroying the disk to take effect. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
 			"id": schema.StringAttribute{
 				Computed:            true,
 				MarkdownDescription: "Disk identifier (same as name).",
@@ -58,17 +70,47 @@ func (r *LimaDiskResource) Schema(ctx context.Context, req resource.SchemaReques
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				Read:              true,
+				Update:            true,
+				Delete:            true,
+				CreateDescription: fmt.Sprintf("Time allowed for creating the disk, such as `30m`. Defaults to `%s`.", defaultDiskCreateTimeout),
+				ReadDescription:   fmt.Sprintf("Time allowed for refreshing the disk. Defaults to `%s`.", defaultDiskReadTimeout),
+				UpdateDescription: fmt.Sprintf("Time allowed for resizing the disk. Defaults to `%s`.", defaultDiskUpdateTimeout),
+				DeleteDescription: fmt.Sprintf("Time allowed for deleting the disk. Defaults to `%s`.", defaultDiskDeleteTimeout),
+			}),
+		},
 	}
 }
 
+func (r *LimaDiskResource) IdentitySchema(ctx context.Context, req resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identitySchema("disk")
+}
+
 func (r *LimaDiskResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// No client needed for limactl - it's a local command-line tool
+	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
 		return
 	}
+
+	providerData, ok := req.ProviderData.(*LimaProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *LimaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.providerData = providerData
 }
 
 func (r *LimaDiskResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var data LimaDiskResourceModel
 
 	// Read Terraform plan data into the model
@@ -78,38 +120,61 @@ func (r *LimaDiskResource) Create(ctx context.Context, req resource.CreateReques
 		return
 	}
 
-	args := []string{"disk", "create", data.Name.ValueString()}
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_disk."+name, limactl.OperationCreate)
 
-	args = append(args, fmt.Sprintf("--size=%gG", data.Size.ValueFloat64()))
-	args = append(args, "--format=qcow2")
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultDiskCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "create", duration: createTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
 
-	// Add --tty=false to disable interactive mode
-	args = append(args, "--tty=false")
+	unlock, diags := r.lock(ctx, name, false)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	defer unlock()
 
 	tflog.Debug(ctx, "Creating Lima disk", map[string]any{
-		"command": "limactl " + strings.Join(args, " "),
+		"name": name,
+		"size": data.Size.ValueFloat64(),
 	})
 
-	// Execute limactl disk create command
-	cmd := exec.CommandContext(ctx, "limactl", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to create Lima disk",
-			fmt.Sprintf("Command: limactl %s\nError: %s\nOutput: %s", strings.Join(args, " "), err, string(output)),
+	err := r.providerData.Client.CreateDisk(ctx, name, data.Size.ValueFloat64(), "qcow2")
+	if interrupted(ctx, err) || timedOut(err) {
+		cleanupInterruptedCreate(ctx, &resp.Diagnostics, "disk", name, "limactl disk delete --force "+name, err,
+			func(ctx context.Context) error {
+				_, err := r.providerData.Client.GetDisk(ctx, name)
+				return err
+			},
+			func(ctx context.Context) error {
+				return r.providerData.Client.DeleteDisk(ctx, name)
+			},
 		)
 		return
 	}
+	if err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to create Lima disk", err, diskErrorAttributes)
+		return
+	}
 
 	tflog.Trace(ctx, "Created Lima disk", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 
+	r.providerData.writeOwner(ctx, &resp.Diagnostics, "disk", data.Name.ValueString(), limactl.Owner{})
+
 	// Set the ID to the disk name
 	data.Id = data.Name
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 }
 
 func (r *LimaDiskResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -122,55 +187,63 @@ func (r *LimaDiskResource) Read(ctx context.Context, req resource.ReadRequest, r
 		return
 	}
 
-	// Check if disk exists using limactl disk list --json
-	cmd := exec.CommandContext(ctx, "limactl", "disk", "list", "--json")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to list Lima disks",
-			fmt.Sprintf("Error: %s\nOutput: %s", err, string(output)),
-		)
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_disk."+name, limactl.OperationRead)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "disk", data.Name)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Parse JSON output - limactl disk list --json returns a single JSON object per line
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	found := false
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		var disk struct {
-			Name string `json:"name"`
-		}
+	readTimeout, diags := data.Timeouts.Read(ctx, defaultDiskReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-		if err := json.Unmarshal([]byte(line), &disk); err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to parse disk list JSON",
-				fmt.Sprintf("Error: %s\nLine: %s", err, line),
-			)
+	timeout := operationTimeout{name: "read", duration: readTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
+
+	disk, err := r.providerData.Client.Inventory().Disk(ctx, name)
+	if errors.Is(err, limactl.ErrNotFound) {
+		if r.providerData.Client.DryRun() {
+			// Nothing is created in dry-run mode, keep the planned state
+			tflog.Info(ctx, "Dry run, keeping Lima disk that does not exist", map[string]any{
+				"name": name,
+			})
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 			return
 		}
 
-		if disk.Name == data.Name.ValueString() {
-			found = true
-			break
-		}
-	}
-
-	if !found {
 		// Disk no longer exists, remove from state
 		resp.State.RemoveResource(ctx)
 		return
 	}
+	if err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to list Lima disks", err, diskErrorAttributes)
+		return
+	}
+
+	// Imported disks have no size yet, and resizes outside of Terraform show
+	// up as a diff
+	data.Size = refreshGiB(data.Size, disk.Size)
+
+	// Imported disks have no force_delete yet
+	if data.ForceDelete.IsNull() {
+		data.ForceDelete = types.BoolValue(false)
+	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 }
 
 func (r *LimaDiskResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var plan LimaDiskResourceModel
 	var state LimaDiskResourceModel
 
@@ -182,6 +255,31 @@ func (r *LimaDiskResource) Update(ctx context.Context, req resource.UpdateReques
 		return
 	}
 
+	name := r.providerData.limaName(plan.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_disk."+name, limactl.OperationUpdate)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "disk", plan.Name)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultDiskUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "update", duration: updateTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
+
+	unlock, diags := r.lock(ctx, name, true)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	defer unlock()
+
 	// Check if size has changed
 	if !plan.Size.Equal(state.Size) {
 		// Validate that size is increasing, not decreasing
@@ -195,38 +293,32 @@ func (r *LimaDiskResource) Update(ctx context.Context, req resource.UpdateReques
 		}
 
 		tflog.Debug(ctx, "Resizing Lima disk", map[string]any{
-			"name":     plan.Name.ValueString(),
+			"name":     name,
 			"old_size": state.Size.ValueFloat64(),
 			"new_size": plan.Size.ValueFloat64(),
 		})
 
-		// Build limactl disk resize command
-		args := []string{"disk", "resize", plan.Name.ValueString()}
-		args = append(args, fmt.Sprintf("--size=%gG", plan.Size.ValueFloat64()))
-		args = append(args, "--tty=false")
-
-		// Execute limactl disk resize command
-		cmd := exec.CommandContext(ctx, "limactl", args...)
-		output, err := cmd.CombinedOutput()
+		err := r.providerData.Client.ResizeDisk(ctx, name, plan.Size.ValueFloat64())
 		if err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to resize Lima disk",
-				fmt.Sprintf("Command: limactl %s\nError: %s\nOutput: %s", strings.Join(args, " "), err, string(output)),
-			)
+			timeout.addError(&resp.Diagnostics, "Failed to resize Lima disk", err, diskErrorAttributes)
 			return
 		}
 
 		tflog.Trace(ctx, "Resized Lima disk", map[string]any{
-			"name": plan.Name.ValueString(),
+			"name": name,
 			"size": plan.Size.ValueFloat64(),
 		})
 	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, plan.Name)
 }
 
 func (r *LimaDiskResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var data LimaDiskResourceModel
 
 	// Read Terraform prior state data into the model
@@ -236,27 +328,83 @@ func (r *LimaDiskResource) Delete(ctx context.Context, req resource.DeleteReques
 		return
 	}
 
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_disk."+name, limactl.OperationDelete)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "disk", data.Name)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := data.Timeouts.Delete(ctx, defaultDiskDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "delete", duration: deleteTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
+
+	unlock, diags := r.lock(ctx, name, true)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	defer unlock()
+
+	r.providerData.checkOwner(ctx, &resp.Diagnostics, "disk", data.Name.ValueString(), data.ForceDelete.ValueBool())
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	tflog.Debug(ctx, "Deleting Lima disk", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 
-	// Delete the Lima disk
-	deleteCmd := exec.CommandContext(ctx, "limactl", "disk", "delete", data.Name.ValueString())
-	deleteOutput, deleteErr := deleteCmd.CombinedOutput()
-	if deleteErr != nil {
-		resp.Diagnostics.AddError(
-			"Failed to delete Lima disk",
-			fmt.Sprintf("Command: limactl disk delete %s\nError: %s\nOutput: %s", data.Name.ValueString(), deleteErr, string(deleteOutput)),
-		)
+	err := r.providerData.Client.DeleteDisk(ctx, name)
+	if err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to delete Lima disk", err, diskErrorAttributes)
 		return
 	}
 
 	tflog.Trace(ctx, "Deleted Lima disk", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 }
 
+// lock serializes operations on the disk. With withInstance set it also
+// locks the instance the disk is attached to, so that a resize or delete does
+// not race an edit of that instance.
+func (r *LimaDiskResource) lock(ctx context.Context, name string, withInstance bool) (func(), diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	keys := []string{diskLockKey(name)}
+
+	if withInstance {
+		disk, err := r.providerData.Client.GetDisk(ctx, name)
+		if err != nil && !errors.Is(err, limactl.ErrNotFound) {
+			addLimactlError(&diags, "Failed to list Lima disks", err, diskErrorAttributes)
+			return nil, diags
+		}
+
+		if disk != nil && disk.Instance != "" {
+			keys = append(keys, instanceLockKey(disk.Instance))
+		}
+	}
+
+	unlock, err := r.providerData.locks.Lock(ctx, keys...)
+	if err != nil {
+		diags.AddError(
+			"Failed to lock Lima disk",
+			fmt.Sprintf("Gave up waiting for other operations on disk %q: %s", name, err),
+		)
+		return nil, diags
+	}
+
+	return unlock, diags
+}
+
 func (r *LimaDiskResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	r.providerData.importState(ctx, req, resp, "disk")
 }
@@ -2,9 +2,13 @@ package provider
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
 
 func TestAccLimaDiskResource(t *testing.T) {
@@ -77,6 +81,81 @@ func TestAccLimaDiskResourceResize(t *testing.T) {
 	})
 }
 
+func TestUnitLimaDiskResource(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		// Importing by identity needs Terraform 1.12
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{tfversion.SkipBelow(tfversion.Version1_12_0)},
+		CheckDestroy: func(*terraform.State) error {
+			if fake.Disk("unit-disk") != nil {
+				return fmt.Errorf("disk %q still exists", "unit-disk")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccLimaDiskResourceConfig("unit-disk", 10),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_disk.test", "id", "unit-disk"),
+					testCheckFakeDiskSize(fake, "unit-disk", 10<<30),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "lima_disk.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"timeouts"},
+			},
+			// Import with an import block and the resource identity
+			{
+				ResourceName:    "lima_disk.test",
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+			// Resize, retrying while another process holds the lock
+			{
+				Config:    testAccLimaDiskResourceConfig("unit-disk", 20),
+				PreConfig: func() { fake.Fail("disk resize", "disk is locked by another process") },
+				Check:     testCheckFakeDiskSize(fake, "unit-disk", 20<<30),
+			},
+			// Shrinking is rejected
+			{
+				Config:      testAccLimaDiskResourceConfig("unit-disk", 5),
+				ExpectError: regexp.MustCompile(`can only be increased`),
+			},
+			// Deleted outside of Terraform
+			{
+				PreConfig:          func() { fake.Remove("unit-disk") },
+				Config:             testAccLimaDiskResourceConfig("unit-disk", 20),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+// testCheckFakeDiskSize verifies the size of the named disk of fake.
+func testCheckFakeDiskSize(fake *fakeLimactl, name string, size int64) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		disk := fake.Disk(name)
+		if disk == nil {
+			return fmt.Errorf("disk %q does not exist", name)
+		}
+
+		if disk.Size != size {
+			return fmt.Errorf("expected disk %q to be %d bytes, got %d", name, size, disk.Size)
+		}
+
+		return nil
+	}
+}
+
 func testAccLimaDiskResourceConfig(name string, size float64) string {
 	return fmt.Sprintf(`
 resource "lima_disk" "test" {
@@ -95,3 +174,25 @@ resource "lima_disk" "test" {
 }
 `, name, size, format)
 }
+
+func TestLimaDiskResourceImportPlan(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	config := h.config("lima_disk", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "data"),
+		"size": tftypes.NewValue(tftypes.Number, 10),
+	})
+	created := h.create("lima_disk", config)
+
+	for name, imported := range map[string]*protocolResource{
+		"id":       h.importResource("lima_disk", "data"),
+		"identity": h.importIdentity("lima_disk", created.identity),
+	} {
+		checkSameState(t, created.state, imported.state, "timeouts")
+
+		if changes := h.plan(imported, config).changes(); len(changes) > 0 {
+			t.Errorf("expected no changes after import by %s, got %v", name, changes)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/list/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+var _ list.ListResource = &LimaInstanceListResource{}
+var _ list.ListResourceWithConfigure = &LimaInstanceListResource{}
+
+func NewLimaInstanceListResource() list.ListResource {
+	return &LimaInstanceListResource{}
+}
+
+// LimaInstanceListResource lists the instances in LIMA_HOME, so that
+// `terraform query` can generate import blocks and configuration for them.
+type LimaInstanceListResource struct {
+	providerData *LimaProviderData
+}
+
+type LimaInstanceListModel struct {
+	NamePattern types.String `tfsdk:"name_pattern"`
+	Status      types.String `tfsdk:"status"`
+	VmType      types.String `tfsdk:"vm_type"`
+	Arch        types.String `tfsdk:"arch"`
+}
+
+func (r *LimaInstanceListResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_instance"
+}
+
+func (r *LimaInstanceListResource) ListResourceConfigSchema(ctx context.Context, req list.ListResourceSchemaRequest, resp *list.ListResourceSchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists Lima instances, including the ones not managed by Terraform. All filters are optional and combined. With the provider `name_prefix`, only the instances named with it are listed, and names are matched and returned without it.",
+		Attributes: map[string]schema.Attribute{
+			"name_pattern": schema.StringAttribute{
+				MarkdownDescription: "Shell pattern the instance name must match, such as `dev-*`.",
+				Optional:            true,
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "Only list instances with this status (Running, Stopped, Broken). Case insensitive.",
+				Optional:            true,
+			},
+			"vm_type": schema.StringAttribute{
+				MarkdownDescription: "Only list instances of this virtual machine type (qemu, vz).",
+				Optional:            true,
+			},
+			"arch": schema.StringAttribute{
+				MarkdownDescription: "Only list instances of this machine architecture (x86_64, aarch64, ...).",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *LimaInstanceListResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*LimaProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected List Resource Configure Type",
+			fmt.Sprintf("Expected *LimaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.providerData = providerData
+}
+
+func (r *LimaInstanceListResource) List(ctx context.Context, req list.ListRequest, stream *list.ListResultsStream) {
+	var filter LimaInstanceListModel
+
+	diags := req.Config.Get(ctx, &filter)
+	if diags.HasError() {
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	if _, err := filepath.Match(filter.NamePattern.ValueString(), ""); err != nil {
+		diags.AddAttributeError(
+			path.Root("name_pattern"),
+			"Invalid name_pattern",
+			fmt.Sprintf("%q is not a valid pattern: %s", filter.NamePattern.ValueString(), err),
+		)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	ctx = limactl.WithOperation(ctx, "lima_instance.*", limactl.OperationRead)
+
+	instances, err := r.providerData.Client.ListInstances(ctx)
+	if err != nil {
+		addLimactlError(&diags, "Failed to list Lima instances", err, nil)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	stream.Results = func(push func(list.ListResult) bool) {
+		var count int64
+
+		for i := range instances {
+			inst := &instances[i]
+
+			// Instances outside the name_prefix namespace belong to others.
+			name, ok := r.providerData.configName(inst.Name)
+			if !ok || name == "" || !filter.matches(name, inst) {
+				continue
+			}
+
+			if req.Limit > 0 && count >= req.Limit {
+				return
+			}
+			count++
+
+			result := req.NewListResult(ctx)
+			result.DisplayName = fmt.Sprintf("%s (%s, %s, %s)", name, inst.Status, inst.VMType, inst.Arch)
+
+			r.providerData.setIdentity(ctx, &result.Diagnostics, result.Identity, types.StringValue(name))
+
+			if req.IncludeResource {
+				data, diags := instanceModelFromLima(inst, r.providerData.namePrefix)
+				result.Diagnostics.Append(diags...)
+				result.Diagnostics.Append(result.Resource.Set(ctx, &data)...)
+			}
+
+			if !push(result) {
+				return
+			}
+		}
+	}
+}
+
+// matches reports whether inst, configured as name, passes the filters.
+func (m LimaInstanceListModel) matches(name string, inst *limactl.Instance) bool {
+	if !m.NamePattern.IsNull() {
+		if ok, _ := filepath.Match(m.NamePattern.ValueString(), name); !ok {
+			return false
+		}
+	}
+
+	if !m.Status.IsNull() && !strings.EqualFold(m.Status.ValueString(), inst.Status) {
+		return false
+	}
+
+	if !m.VmType.IsNull() && m.VmType.ValueString() != inst.VMType {
+		return false
+	}
+
+	if !m.Arch.IsNull() && m.Arch.ValueString() != inst.Arch {
+		return false
+	}
+
+	return true
+}
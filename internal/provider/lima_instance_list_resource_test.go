@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// testListResults runs a list resource against fake with the given provider
+// name_prefix and filters, and returns the results.
+func testListResults(t *testing.T, fake *fakeLimactl, namePrefix string, lr list.ListResource, r resource.ResourceWithIdentity, filters map[string]string, includeResource bool) []list.ListResult {
+	t.Helper()
+	ctx := context.Background()
+
+	var configResp list.ListResourceSchemaResponse
+	lr.ListResourceConfigSchema(ctx, list.ListResourceSchemaRequest{}, &configResp)
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+
+	var identityResp resource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, resource.IdentitySchemaRequest{}, &identityResp)
+
+	configType, ok := configResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	if !ok {
+		t.Fatal("list config schema is not an object")
+	}
+
+	values := map[string]tftypes.Value{}
+	for name := range configType.AttributeTypes {
+		var value any
+		if v, ok := filters[name]; ok {
+			value = v
+		}
+		values[name] = tftypes.NewValue(tftypes.String, value)
+	}
+
+	data := &LimaProviderData{
+		Client:     limactl.New(limactl.Config{Transport: fake, LimaHome: "/home/me/.lima"}),
+		host:       localHost,
+		namePrefix: namePrefix,
+	}
+
+	configurable, ok := lr.(list.ListResourceWithConfigure)
+	if !ok {
+		t.Fatal("list resource cannot be configured")
+	}
+	configurable.Configure(ctx, resource.ConfigureRequest{ProviderData: data}, &resource.ConfigureResponse{})
+
+	stream := &list.ListResultsStream{}
+	lr.List(ctx, list.ListRequest{
+		Config:                 tfsdk.Config{Schema: configResp.Schema, Raw: tftypes.NewValue(configType, values)},
+		IncludeResource:        includeResource,
+		ResourceSchema:         schemaResp.Schema,
+		ResourceIdentitySchema: identityResp.IdentitySchema,
+	}, stream)
+
+	var results []list.ListResult
+	for result := range stream.Results {
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func TestLimaInstanceListResource(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeLimactl()
+	fake.instances["dev-1"] = &limactl.Instance{Name: "dev-1", Status: limactl.StatusRunning, VMType: "vz", Arch: "aarch64", CPUs: 2, Memory: 4 << 30, Disk: 50 << 30}
+	fake.instances["dev-2"] = &limactl.Instance{Name: "dev-2", Status: limactl.StatusStopped, VMType: "qemu", Arch: "x86_64", CPUs: 4, Memory: 8 << 30, Disk: 100 << 30}
+	fake.instances["ci"] = &limactl.Instance{
+		Name: "ci", Status: limactl.StatusRunning, VMType: "qemu", Arch: "x86_64", CPUs: 4, Memory: 8 << 30, Disk: 100 << 30,
+		AdditionalDisks: []limactl.AdditionalDisk{{Name: "cache", MountPoint: "/mnt/cache"}},
+		Config:          &limactl.InstanceConfig{DNS: []string{"1.1.1.1"}},
+	}
+
+	tests := map[string]struct {
+		filters map[string]string
+		names   []string
+	}{
+		"all":          {names: []string{"ci", "dev-1", "dev-2"}},
+		"name pattern": {filters: map[string]string{"name_pattern": "dev-*"}, names: []string{"dev-1", "dev-2"}},
+		"status":       {filters: map[string]string{"status": "running"}, names: []string{"ci", "dev-1"}},
+		"vm type":      {filters: map[string]string{"vm_type": "qemu"}, names: []string{"ci", "dev-2"}},
+		"arch":         {filters: map[string]string{"arch": "aarch64"}, names: []string{"dev-1"}},
+		"combined":     {filters: map[string]string{"name_pattern": "dev-*", "status": "Stopped"}, names: []string{"dev-2"}},
+		"no match":     {filters: map[string]string{"name_pattern": "prod-*"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			results := testListResults(t, fake, "", NewLimaInstanceListResource(), &LimaInstanceResource{}, tt.filters, false)
+
+			var names []string
+			for _, result := range results {
+				if result.Diagnostics.HasError() {
+					t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+				}
+
+				var identity LimaIdentityModel
+				result.Identity.Get(ctx, &identity)
+				if identity.LimaHome.ValueString() != "/home/me/.lima" || identity.Host.ValueString() != localHost {
+					t.Errorf("unexpected identity %+v", identity)
+				}
+
+				names = append(names, identity.Name.ValueString())
+			}
+
+			if len(names) != len(tt.names) {
+				t.Fatalf("expected %v, got %v", tt.names, names)
+			}
+			for i := range names {
+				if names[i] != tt.names[i] {
+					t.Fatalf("expected %v, got %v", tt.names, names)
+				}
+			}
+		})
+	}
+
+	t.Run("resource", func(t *testing.T) {
+		results := testListResults(t, fake, "", NewLimaInstanceListResource(), &LimaInstanceResource{}, map[string]string{"name_pattern": "ci"}, true)
+		if len(results) != 1 {
+			t.Fatalf("expected one result, got %d", len(results))
+		}
+		if results[0].Diagnostics.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", results[0].Diagnostics)
+		}
+
+		var data LimaInstanceResourceModel
+		if diags := results[0].Resource.Get(ctx, &data); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+
+		if data.Name.ValueString() != "ci" || data.Cpus.ValueInt64() != 4 || data.Memory.ValueFloat64() != 8 || data.VmType.ValueString() != "qemu" {
+			t.Errorf("unexpected resource %+v", data)
+		}
+		if len(data.Disks.Elements()) != 1 || len(data.DNS.Elements()) != 1 {
+			t.Errorf("expected the additional disk and dns server, got %v and %v", data.Disks, data.DNS)
+		}
+	})
+
+	t.Run("name prefix", func(t *testing.T) {
+		prefixed := newFakeLimactl()
+		prefixed.instances["alice-dev"] = &limactl.Instance{Name: "alice-dev", Status: limactl.StatusRunning, AdditionalDisks: []limactl.AdditionalDisk{{Name: "alice-data"}}}
+		prefixed.instances["bob-dev"] = &limactl.Instance{Name: "bob-dev", Status: limactl.StatusRunning}
+
+		results := testListResults(t, prefixed, "alice-", NewLimaInstanceListResource(), &LimaInstanceResource{}, map[string]string{"name_pattern": "dev"}, true)
+		if len(results) != 1 {
+			t.Fatalf("expected one result, got %d", len(results))
+		}
+
+		var identity LimaIdentityModel
+		results[0].Identity.Get(ctx, &identity)
+		if identity.Name.ValueString() != "alice-dev" {
+			t.Errorf("expected the identity to hold the limactl name, got %q", identity.Name.ValueString())
+		}
+
+		var data LimaInstanceResourceModel
+		results[0].Resource.Get(ctx, &data)
+
+		var disks []DisksModel
+		data.Disks.ElementsAs(ctx, &disks, false)
+		if data.Name.ValueString() != "dev" || len(disks) != 1 || disks[0].Name.ValueString() != "data" {
+			t.Errorf("expected the prefix to be stripped, got %v and %v", data.Name, data.Disks)
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		results := testListResults(t, fake, "", NewLimaInstanceListResource(), &LimaInstanceResource{}, map[string]string{"name_pattern": "dev-["}, false)
+		if len(results) != 1 || !results[0].Diagnostics.HasError() {
+			t.Fatalf("expected an error, got %v", results)
+		}
+	})
+}
+
+func TestInstanceModelFromLima(t *testing.T) {
+	yes := true
+	display := "vnc"
+	inst := &limactl.Instance{
+		Name: "dev", Status: limactl.StatusStopped, VMType: "vz", Arch: "aarch64", CPUs: 2, Memory: 3 << 29, Disk: 20 << 30,
+		Config: &limactl.InstanceConfig{
+			Containerd: limactl.Containerd{System: &yes},
+			Mounts:     []limactl.Mount{{Location: "~"}, {Location: "/tmp/lima", Writable: &yes}},
+			Networks:   []limactl.Network{{Lima: "shared"}, {VZNAT: &yes}},
+			Video:      limactl.Video{Display: &display},
+		},
+	}
+
+	data, diags := instanceModelFromLima(inst, "")
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if data.Memory.ValueFloat64() != 1.5 || data.Disk.ValueFloat64() != 20 {
+		t.Errorf("expected 1.5GiB memory and 20GiB disk, got %v and %v", data.Memory, data.Disk)
+	}
+	if data.Containerd.ValueString() != "system" {
+		t.Errorf("expected containerd system, got %v", data.Containerd)
+	}
+	if !data.Video.ValueBool() {
+		t.Error("expected video to be enabled")
+	}
+	if data.Running.ValueBool() {
+		t.Error("expected the stopped instance not to be running")
+	}
+	if !data.Template.IsNull() {
+		t.Errorf("expected no template, got %v", data.Template)
+	}
+
+	expectList := func(attribute string, got types.List, want ...string) {
+		t.Helper()
+
+		var values []string
+		got.ElementsAs(context.Background(), &values, false)
+		if len(values) != len(want) {
+			t.Fatalf("expected %s %v, got %v", attribute, want, values)
+		}
+		for i := range want {
+			if values[i] != want[i] {
+				t.Fatalf("expected %s %v, got %v", attribute, want, values)
+			}
+		}
+	}
+
+	expectList("mount", data.Mount, "~", "/tmp/lima:w")
+	expectList("network", data.Network, "lima:shared", "vzNAT")
+
+	if !data.DNS.IsNull() {
+		t.Errorf("expected no dns, got %v", data.DNS)
+	}
+}
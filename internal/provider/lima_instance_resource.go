@@ -2,52 +2,60 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
 )
 
 var _ resource.Resource = &LimaInstanceResource{}
 var _ resource.ResourceWithImportState = &LimaInstanceResource{}
+var _ resource.ResourceWithIdentity = &LimaInstanceResource{}
+var _ resource.ResourceWithModifyPlan = &LimaInstanceResource{}
 
 func NewLimaInstanceResource() resource.Resource {
 	return &LimaInstanceResource{}
 }
 
-type LimaInstanceResource struct{}
+type LimaInstanceResource struct {
+	providerData *LimaProviderData
+}
 
 type LimaInstanceResourceModel struct {
-	Name          types.String  `tfsdk:"name"`
-	Template      types.String  `tfsdk:"template"`
-	Arch          types.String  `tfsdk:"arch"`
-	Containerd    types.String  `tfsdk:"containerd"`
-	Cpus          types.Int64   `tfsdk:"cpus"`
-	Disk          types.Float64 `tfsdk:"disk"`
-	Memory        types.Float64 `tfsdk:"memory"`
-	DNS           types.List    `tfsdk:"dns"`
-	Mount         types.List    `tfsdk:"mount"`
-	MountInotify  types.Bool    `tfsdk:"mount_inotify"`
-	MountNone     types.Bool    `tfsdk:"mount_none"`
-	MountType     types.String  `tfsdk:"mount_type"`
-	MountWritable types.Bool    `tfsdk:"mount_writable"`
-	Network       types.List    `tfsdk:"network"`
-	Plain         types.Bool    `tfsdk:"plain"`
-	Rosetta       types.Bool    `tfsdk:"rosetta"`
-	Video         types.Bool    `tfsdk:"video"`
-	VmType        types.String  `tfsdk:"vm_type"`
-	Disks         types.List    `tfsdk:"disks"`
-	Id            types.String  `tfsdk:"id"`
+	Name          types.String   `tfsdk:"name"`
+	Template      types.String   `tfsdk:"template"`
+	Arch          types.String   `tfsdk:"arch"`
+	Containerd    types.String   `tfsdk:"containerd"`
+	Cpus          types.Int64    `tfsdk:"cpus"`
+	Disk          types.Float64  `tfsdk:"disk"`
+	Memory        types.Float64  `tfsdk:"memory"`
+	DNS           types.List     `tfsdk:"dns"`
+	Mount         types.List     `tfsdk:"mount"`
+	MountInotify  types.Bool     `tfsdk:"mount_inotify"`
+	MountNone     types.Bool     `tfsdk:"mount_none"`
+	MountType     types.String   `tfsdk:"mount_type"`
+	MountWritable types.Bool     `tfsdk:"mount_writable"`
+	Network       types.List     `tfsdk:"network"`
+	Plain         types.Bool     `tfsdk:"plain"`
+	Rosetta       types.Bool     `tfsdk:"rosetta"`
+	Video         types.Bool     `tfsdk:"video"`
+	VmType        types.String   `tfsdk:"vm_type"`
+	Disks         types.List     `tfsdk:"disks"`
+	Running       types.Bool     `tfsdk:"running"`
+	ForceDelete   types.Bool     `tfsdk:"force_delete"`
+	Id            types.String   `tfsdk:"id"`
+	Timeouts      timeouts.Value `tfsdk:"timeouts"`
 }
 
 type DisksModel struct {
@@ -61,8 +69,11 @@ func (r *LimaInstanceResource) Metadata(ctx context.Context, req resource.Metada
 
 func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Lima instance resource. Creates and manages a lightweight VM using limactl.",
+		MarkdownDescription: "Lima instance resource. Creates and manages a lightweight VM using limactl. Refreshing reports changes made outside of Terraform, such as `limactl edit`. Attributes the configuration does not set, including those of imported instances, take the values of the instance; once `mount` or `network` is set, only the configured entries are tracked.",
 
+		// The attributes in instanceDefaults are Computed so that ModifyPlan
+		// can fill in the provider defaults, which also decides when they
+		// require replacement.
 		Attributes: map[string]schema.Attribute{
 			"name": schema.StringAttribute{
 				MarkdownDescription: "Name of the Lima instance. If not specified, defaults to 'default'.",
@@ -74,45 +85,44 @@ func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRe
 			"template": schema.StringAttribute{
 				MarkdownDescription: "Template to use for the instance. Can be a template name (e.g., 'docker'), local file path, or URL. If not specified, uses the default Ubuntu template.",
 				Optional:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
+				Computed:            true,
 			},
 			"arch": schema.StringAttribute{
 				MarkdownDescription: "Machine architecture (x86_64, aarch64, riscv64, armv7l, s390x, ppc64le).",
 				Optional:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
+				Computed:            true,
 			},
 			"containerd": schema.StringAttribute{
 				MarkdownDescription: "Containerd mode (user, system, user+system, none).",
 				Optional:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
+				Computed:            true,
 			},
 			"cpus": schema.Int64Attribute{
 				MarkdownDescription: "Number of CPUs to allocate to the instance.",
 				Optional:            true,
+				Computed:            true,
 			},
 			"disk": schema.Float64Attribute{
 				MarkdownDescription: "Disk size in GiB.",
 				Optional:            true,
+				Computed:            true,
 			},
 			"memory": schema.Float64Attribute{
 				MarkdownDescription: "Memory in GiB.",
 				Optional:            true,
+				Computed:            true,
 			},
 			"dns": schema.ListAttribute{
 				MarkdownDescription: "Custom DNS servers (disables host resolver).",
 				ElementType:         types.StringType,
 				Optional:            true,
+				Computed:            true,
 			},
 			"mount": schema.ListAttribute{
 				MarkdownDescription: "Directories to mount. Suffix ':w' for writable. Do not specify directories that overlap with existing mounts.",
 				ElementType:         types.StringType,
 				Optional:            true,
+				Computed:            true,
 			},
 			"mount_inotify": schema.BoolAttribute{
 				MarkdownDescription: "Enable inotify for mounts.",
@@ -125,16 +135,14 @@ func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRe
 				Optional:            true,
 				Computed:            true,
 				Default:             booldefault.StaticBool(false),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.RequiresReplace(),
-				},
 			},
 			"mount_type": schema.StringAttribute{
 				MarkdownDescription: "Mount type (reverse-sshfs, 9p, virtiofs).",
 				Optional:            true,
+				Computed:            true,
 			},
 			"mount_writable": schema.BoolAttribute{
-				MarkdownDescription: "Make all mounts writable.",
+				MarkdownDescription: "Make all mounts writable. Turning it off makes every mount read-only, the template ones too, except those `mount` marks writable.",
 				Optional:            true,
 				Computed:            true,
 				Default:             booldefault.StaticBool(false),
@@ -143,15 +151,13 @@ func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRe
 				MarkdownDescription: "Additional networks, e.g., 'vzNAT' or 'lima:shared' to assign vmnet IP.",
 				ElementType:         types.StringType,
 				Optional:            true,
+				Computed:            true,
 			},
 			"plain": schema.BoolAttribute{
 				MarkdownDescription: "Plain mode. Disables mounts, port forwarding, containerd, etc.",
 				Optional:            true,
 				Computed:            true,
 				Default:             booldefault.StaticBool(false),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.RequiresReplace(),
-				},
 			},
 			"rosetta": schema.BoolAttribute{
 				MarkdownDescription: "Enable Rosetta (for vz instances on macOS).",
@@ -168,9 +174,19 @@ func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRe
 			"vm_type": schema.StringAttribute{
 				MarkdownDescription: "Virtual machine type (qemu, vz).",
 				Optional:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
+				Computed:            true,
+			},
+			"running": schema.BoolAttribute{
+				MarkdownDescription: "Whether the instance should be running. Set to false to keep a stopped instance; changing it starts or stops the instance in place. Refreshing reports instances stopped outside of Terraform. Defaults to true.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"force_delete": schema.BoolAttribute{
+				MarkdownDescription: "Delete the instance even if it was not created by this workspace, for example after importing it. Must be applied before destroying the instance to take effect. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
 			},
 			"id": schema.StringAttribute{
 				Computed:            true,
@@ -196,18 +212,120 @@ func (r *LimaInstanceResource) Schema(ctx context.Context, req resource.SchemaRe
 					},
 				},
 			},
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				Read:              true,
+				Update:            true,
+				Delete:            true,
+				CreateDescription: fmt.Sprintf("Time allowed for creating and first booting the instance, such as `30m`. Defaults to `%s`.", defaultInstanceCreateTimeout),
+				ReadDescription:   fmt.Sprintf("Time allowed for refreshing the instance. Defaults to `%s`.", defaultInstanceReadTimeout),
+				UpdateDescription: fmt.Sprintf("Time allowed for the whole stop, edit and start sequence. Defaults to `%s`.", defaultInstanceUpdateTimeout),
+				DeleteDescription: fmt.Sprintf("Time allowed for deleting the instance. Defaults to `%s`.", defaultInstanceDeleteTimeout),
+			}),
 		},
 	}
 }
 
+func (r *LimaInstanceResource) IdentitySchema(ctx context.Context, req resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identitySchema("instance")
+}
+
 func (r *LimaInstanceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// No client needed for limactl - it's a local command-line tool
+	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
 		return
 	}
+
+	providerData, ok := req.ProviderData.(*LimaProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *LimaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.providerData = providerData
+}
+
+// instanceFeatures maps attributes to the limactl feature they depend on.
+var instanceFeatures = map[string]limactl.Feature{
+	"mount_inotify": limactl.FeatureMountInotify,
+	"mount_none":    limactl.FeatureMountNone,
+}
+
+func (r *LimaInstanceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Without the provider configuration, such as during validation, only
+	// the unset attributes are resolved.
+	if r.providerData == nil {
+		if !req.Plan.Raw.IsNull() {
+			applyInstanceDefaults(ctx, nil, req, resp)
+		}
+		return
+	}
+
+	if req.Plan.Raw.IsNull() {
+		var name types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &name)...)
+		r.providerData.capacity.destroy(r.providerData.limaName(name.ValueString()))
+		return
+	}
+
+	applyInstanceDefaults(ctx, r.providerData.instanceDefaults, req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var name, stateName types.String
+	var cpus types.Int64
+	var memory types.Float64
+	var running types.Bool
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("running"), &running)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("cpus"), &cpus)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("memory"), &memory)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &stateName)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A renamed instance replaces the old one.
+	if !stateName.IsNull() && !stateName.Equal(name) {
+		r.providerData.capacity.destroy(r.providerData.limaName(stateName.ValueString()))
+	}
+	if running.IsNull() || running.IsUnknown() || running.ValueBool() {
+		r.providerData.capacity.plan(ctx, &resp.Diagnostics, r.providerData.limaName(name.ValueString()), cpus, memory)
+	} else {
+		// A stopped instance uses neither CPUs nor memory.
+		r.providerData.capacity.destroy(r.providerData.limaName(name.ValueString()))
+	}
+
+	client := r.providerData.Client
+
+	for attr, feature := range instanceFeatures {
+		if client.Supports(feature) {
+			continue
+		}
+
+		var enabled types.Bool
+		resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root(attr), &enabled)...)
+
+		if enabled.ValueBool() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unsupported limactl version",
+				fmt.Sprintf("%s requires limactl %s or later (%s), but limactl %s is installed.", attr, feature.MinVersion, feature.Name, client.Version()),
+			)
+		}
+	}
 }
 
 func (r *LimaInstanceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var data LimaInstanceResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -215,11 +333,34 @@ func (r *LimaInstanceResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
-	args := []string{"create"}
+	configured, diags := newConfiguredAttributes(ctx, req.Config, r.providerData.instanceDefaults)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(writeConfiguredAttributes(ctx, resp.Private, configured)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_instance."+name, limactl.OperationCreate)
+
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultInstanceCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "create", duration: createTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
 
-	if !data.Name.IsNull() {
-		args = append(args, "--name="+data.Name.ValueString())
+	unlock, diags := r.lock(ctx, name, data.Disks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
+	defer unlock()
+
+	var args []string
 
 	if !data.Arch.IsNull() {
 		args = append(args, "--arch="+data.Arch.ValueString())
@@ -307,100 +448,112 @@ func (r *LimaInstanceResource) Create(ctx context.Context, req resource.CreateRe
 	}
 
 	if !data.Disks.IsNull() && len(data.Disks.Elements()) > 0 {
-		var disks []DisksModel
-		resp.Diagnostics.Append(data.Disks.ElementsAs(ctx, &disks, false)...)
+		expr, diags := r.additionalDisksExpression(ctx, data.Disks)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
-		type diskJSON struct {
-			Name       string `json:"name"`
-			MountPoint string `json:"mountPoint"`
-		}
-
-		var diskArray []diskJSON
-		for _, disk := range disks {
-			diskArray = append(diskArray, diskJSON{
-				Name:       disk.Name.ValueString(),
-				MountPoint: disk.MountPoint.ValueString(),
-			})
-		}
-
-		diskJSONBytes, err := json.Marshal(diskArray)
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to marshal additional disks",
-				fmt.Sprintf("Error: %s", err),
-			)
-			return
-		}
-
-		args = append(args, fmt.Sprintf("--set=.additionalDisks=%s", string(diskJSONBytes)))
+		args = append(args, "--set="+expr)
 	}
 
-	// Add --tty=false for non-interactive use (automation)
-	args = append(args, "--tty=false")
-
+	template := ""
 	if !data.Template.IsNull() {
-		template := data.Template.ValueString()
+		template = data.Template.ValueString()
 		if !strings.HasPrefix(template, "http") && !strings.HasSuffix(template, ".yaml") {
 			template = "template://" + template
 		}
-		args = append(args, template)
 	}
 
 	tflog.Debug(ctx, "Creating Lima instance", map[string]any{
-		"command": "limactl " + strings.Join(args, " "),
+		"name": name,
 	})
 
-	cmd := exec.CommandContext(ctx, "limactl", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to create Lima instance",
-			fmt.Sprintf("Command: limactl %s\nError: %s\nOutput: %s", strings.Join(args, " "), err, string(output)),
+	err := r.providerData.Client.CreateInstance(ctx, name, template, args)
+	if interrupted(ctx, err) || timedOut(err) {
+		cleanupInterruptedCreate(ctx, &resp.Diagnostics, "instance", name, "limactl delete --force "+name, err,
+			func(ctx context.Context) error {
+				_, err := r.providerData.Client.GetInstance(ctx, name)
+				return err
+			},
+			func(ctx context.Context) error {
+				return r.providerData.Client.DeleteInstance(ctx, name)
+			},
 		)
 		return
 	}
+	if err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to create Lima instance", err, instanceErrorAttributes)
+		return
+	}
 
 	tflog.Trace(ctx, "Created Lima instance", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
+	})
+
+	r.providerData.writeOwner(ctx, &resp.Diagnostics, "instance", data.Name.ValueString(), limactl.Owner{
+		Template:  data.Template.ValueString(),
+		MountNone: data.MountNone.ValueBool(),
 	})
 
+	// An instance that should be stopped is only created
+	if !data.Running.ValueBool() {
+		data.Id = data.Name
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
+		return
+	}
+
 	tflog.Debug(ctx, "Starting Lima instance", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 
-	startCmd := exec.CommandContext(ctx, "limactl", "start", data.Name.ValueString())
-	startOutput, startErr := startCmd.CombinedOutput()
+	startErr := r.start(ctx, name)
+	if interrupted(ctx, startErr) {
+		// The instance exists, keep it in state so that Terraform taints it
+		// instead of losing track of it.
+		data.Id = data.Name
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
+		resp.Diagnostics.AddError(
+			"Lima instance start interrupted",
+			fmt.Sprintf("Instance %q was created but starting it was interrupted. It was saved in state as tainted and the next apply replaces it. "+
+				"To keep it instead, run `terraform untaint` on the resource and `limactl start %s`.\n\n%s", name, name, startErr),
+		)
+		return
+	}
 	if startErr != nil {
+		// A timed out ctx cannot run the cleanup
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+
+		// The logs are deleted with the instance
+		logs := r.providerData.startFailureLogs(ctx, name)
+
 		// Clean up the created instance if start fails
 		tflog.Warn(ctx, "Start failed, cleaning up created instance", map[string]any{
-			"name": data.Name.ValueString(),
+			"name": name,
 		})
-		deleteCmd := exec.CommandContext(ctx, "limactl", "delete", data.Name.ValueString())
-		deleteOutput, deleteErr := deleteCmd.CombinedOutput()
+
+		deleteErr := r.providerData.Client.DeleteInstance(ctx, name)
 		if deleteErr != nil {
 			tflog.Error(ctx, "Failed to clean up instance after start failure", map[string]any{
-				"name":   data.Name.ValueString(),
-				"error":  deleteErr.Error(),
-				"output": string(deleteOutput),
+				"name":  name,
+				"error": deleteErr.Error(),
 			})
 		}
 
-		resp.Diagnostics.AddError(
-			"Failed to start Lima instance",
-			fmt.Sprintf("Command: limactl start %s\nError: %s\nOutput: %s", data.Name.ValueString(), startErr, string(startOutput)),
-		)
+		timeout.addError(&resp.Diagnostics, "Failed to start Lima instance", fmt.Errorf("%w%s", startErr, logs), instanceErrorAttributes)
 		return
 	}
 
 	tflog.Trace(ctx, "Started Lima instance", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 
 	data.Id = data.Name
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 }
 
 func (r *LimaInstanceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -412,55 +565,99 @@ func (r *LimaInstanceResource) Read(ctx context.Context, req resource.ReadReques
 		return
 	}
 
-	// Check if instance exists using limactl list --json
-	cmd := exec.CommandContext(ctx, "limactl", "list", "--json")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to list Lima instances",
-			fmt.Sprintf("Error: %s\nOutput: %s", err, string(output)),
-		)
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_instance."+name, limactl.OperationRead)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "instance", data.Name)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Parse JSON output - limactl list --json returns a single JSON object per line
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	found := false
+	// Record what imported instances and those applied by earlier versions
+	// configure, before refreshing fills in the unset attributes.
+	configured, diags := readConfiguredAttributes(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	recorded := configured != nil
+	if !recorded && !resp.Diagnostics.HasError() {
+		configured, diags = stateConfiguredAttributes(ctx, req.State)
+		resp.Diagnostics.Append(diags...)
+		resp.Diagnostics.Append(writeConfiguredAttributes(ctx, resp.Private, configured)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
+	readTimeout, diags := data.Timeouts.Read(ctx, defaultInstanceReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-		var instance struct {
-			Name string `json:"name"`
-		}
+	timeout := operationTimeout{name: "read", duration: readTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
 
-		if err := json.Unmarshal([]byte(line), &instance); err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to parse instance list JSON",
-				fmt.Sprintf("Error: %s\nLine: %s", err, line),
-			)
+	inst, err := r.providerData.Client.Inventory().Instance(ctx, name)
+	if errors.Is(err, limactl.ErrNotFound) {
+		if r.providerData.Client.DryRun() {
+			// Nothing is created in dry-run mode, keep the planned state
+			tflog.Info(ctx, "Dry run, keeping Lima instance that does not exist", map[string]any{
+				"name": name,
+			})
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 			return
 		}
 
-		if instance.Name == data.Name.ValueString() {
-			found = true
-			break
+		// Instance no longer exists, remove from state
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to list Lima instances", err, instanceErrorAttributes)
+		return
+	}
+
+	// Imported instances take the create-time settings that their
+	// configuration does not show from the ownership marker
+	if !recorded && inst.Dir != "" {
+		owner, err := r.providerData.Client.ReadOwner(ctx, inst.Dir)
+		if err != nil {
+			tflog.Warn(ctx, "Failed to read ownership marker", map[string]any{
+				"name":  name,
+				"error": err.Error(),
+			})
+		}
+		if owner != nil {
+			if data.Template.IsNull() && owner.Template != "" {
+				data.Template = types.StringValue(owner.Template)
+			}
+			if data.MountNone.IsNull() {
+				data.MountNone = types.BoolValue(owner.MountNone)
+			}
 		}
 	}
 
-	if !found {
-		// Instance no longer exists, remove from state
-		resp.State.RemoveResource(ctx)
+	// Changes made outside of Terraform show up as a diff
+	resp.Diagnostics.Append(refreshInstanceModel(ctx, &data, inst, r.providerData.namePrefix)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	// Imported instances have no force_delete yet
+	if data.ForceDelete.IsNull() {
+		data.ForceDelete = types.BoolValue(false)
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, data.Name)
 }
 
 func (r *LimaInstanceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var plan LimaInstanceResourceModel
 	var state LimaInstanceResourceModel
 
@@ -472,8 +669,43 @@ func (r *LimaInstanceResource) Update(ctx context.Context, req resource.UpdateRe
 		return
 	}
 
-	// Build limactl edit command
-	args := []string{"edit", plan.Name.ValueString()}
+	// The private state of the request is that of the response
+	priorConfigured, diags := readConfiguredAttributes(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	configured, diags := newConfiguredAttributes(ctx, req.Config, r.providerData.instanceDefaults)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(writeConfiguredAttributes(ctx, resp.Private, configured)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := r.providerData.limaName(plan.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_instance."+name, limactl.OperationUpdate)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "instance", plan.Name)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultInstanceUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "update", duration: updateTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
+
+	unlock, diags := r.lock(ctx, name, plan.Disks, state.Disks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	defer unlock()
+
+	// Build limactl edit flags
+	var args []string
 
 	// Add flags for changed attributes that are supported by limactl edit
 	if !plan.Cpus.IsNull() && !plan.Cpus.Equal(state.Cpus) {
@@ -488,129 +720,91 @@ func (r *LimaInstanceResource) Update(ctx context.Context, req resource.UpdateRe
 		args = append(args, fmt.Sprintf("--memory=%g", plan.Memory.ValueFloat64()))
 	}
 
-	// DNS flags
-	if !plan.DNS.Equal(state.DNS) {
-		if !plan.DNS.IsNull() && len(plan.DNS.Elements()) > 0 {
-			var dnsServers []string
-			resp.Diagnostics.Append(plan.DNS.ElementsAs(ctx, &dnsServers, false)...)
-			if resp.Diagnostics.HasError() {
-				return
-			}
-			for _, dns := range dnsServers {
-				args = append(args, "--dns="+dns)
-			}
-		}
-	}
-
-	// Mount flags
-	if !plan.Mount.Equal(state.Mount) {
-		if !plan.Mount.IsNull() && len(plan.Mount.Elements()) > 0 {
-			var mounts []string
-			resp.Diagnostics.Append(plan.Mount.ElementsAs(ctx, &mounts, false)...)
-			if resp.Diagnostics.HasError() {
-				return
-			}
-			for _, mount := range mounts {
-				args = append(args, "--mount="+mount)
-			}
-		}
-	}
-
-	if !plan.MountInotify.Equal(state.MountInotify) && plan.MountInotify.ValueBool() {
-		args = append(args, "--mount-inotify")
-	}
-
 	if !plan.MountType.IsNull() && !plan.MountType.Equal(state.MountType) {
 		args = append(args, "--mount-type="+plan.MountType.ValueString())
 	}
 
-	if !plan.MountWritable.Equal(state.MountWritable) && plan.MountWritable.ValueBool() {
-		args = append(args, "--mount-writable")
-	}
-
-	// Network flags
-	if !plan.Network.Equal(state.Network) {
-		if !plan.Network.IsNull() && len(plan.Network.Elements()) > 0 {
-			var networks []string
-			resp.Diagnostics.Append(plan.Network.ElementsAs(ctx, &networks, false)...)
-			if resp.Diagnostics.HasError() {
-				return
-			}
-			for _, network := range networks {
-				args = append(args, "--network="+network)
-			}
-		}
+	// Everything else is replaced through lima.yaml, so that removals apply
+	expr, diags := r.setExpression(ctx, plan, state, priorConfigured)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-
-	if !plan.Rosetta.Equal(state.Rosetta) && plan.Rosetta.ValueBool() {
-		args = append(args, "--rosetta")
+	if expr != "" {
+		args = append(args, "--set="+expr)
 	}
 
-	if !plan.Video.Equal(state.Video) && plan.Video.ValueBool() {
-		args = append(args, "--video")
-	}
+	// A stopped instance stays stopped, unless running changes too
+	stopped := !state.Running.IsNull() && !state.Running.ValueBool()
 
 	// Only proceed with edit if there are actual changes
-	if len(args) > 2 { // More than just "edit" and instance name
+	if len(args) > 0 {
 		tflog.Debug(ctx, "Editing Lima instance", map[string]any{
-			"command": "limactl " + strings.Join(args, " "),
+			"name":  name,
+			"flags": args,
 		})
 
 		// First stop the instance
-		tflog.Debug(ctx, "Stopping Lima instance for edit", map[string]any{
-			"name": plan.Name.ValueString(),
-		})
+		if !stopped {
+			tflog.Debug(ctx, "Stopping Lima instance for edit", map[string]any{
+				"name": name,
+			})
 
-		stopCmd := exec.CommandContext(ctx, "limactl", "stop", plan.Name.ValueString())
-		stopOutput, stopErr := stopCmd.CombinedOutput()
-		if stopErr != nil {
-			resp.Diagnostics.AddError(
-				"Failed to stop Lima instance for edit",
-				fmt.Sprintf("Command: limactl stop %s\nError: %s\nOutput: %s", plan.Name.ValueString(), stopErr, string(stopOutput)),
-			)
-			return
+			if err := r.providerData.Client.StopInstance(ctx, name); err != nil {
+				timeout.addError(&resp.Diagnostics, "Failed to stop Lima instance for edit", err, instanceErrorAttributes)
+				return
+			}
+			stopped = true
 		}
 
-		// Execute limactl edit command
-		cmd := exec.CommandContext(ctx, "limactl", args...)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to edit Lima instance",
-				fmt.Sprintf("Command: limactl %s\nError: %s\nOutput: %s", strings.Join(args, " "), err, string(output)),
-			)
+		if err := r.providerData.Client.EditInstance(ctx, name, args); err != nil {
+			timeout.addError(&resp.Diagnostics, "Failed to edit Lima instance", err, instanceErrorAttributes)
 			return
 		}
 
 		tflog.Trace(ctx, "Edited Lima instance", map[string]any{
-			"name": plan.Name.ValueString(),
+			"name": name,
 		})
+	}
 
-		// Start the instance again
-		tflog.Debug(ctx, "Starting Lima instance after edit", map[string]any{
-			"name": plan.Name.ValueString(),
+	switch {
+	case plan.Running.ValueBool() && stopped:
+		tflog.Debug(ctx, "Starting Lima instance", map[string]any{
+			"name": name,
 		})
 
-		startCmd := exec.CommandContext(ctx, "limactl", "start", plan.Name.ValueString())
-		startOutput, startErr := startCmd.CombinedOutput()
-		if startErr != nil {
-			resp.Diagnostics.AddError(
-				"Failed to start Lima instance after edit",
-				fmt.Sprintf("Command: limactl start %s\nError: %s\nOutput: %s", plan.Name.ValueString(), startErr, string(startOutput)),
-			)
+		if err := r.start(ctx, name); err != nil {
+			timeout.addError(&resp.Diagnostics, "Failed to start Lima instance", err, instanceErrorAttributes)
 			return
 		}
 
-		tflog.Trace(ctx, "Started Lima instance after edit", map[string]any{
-			"name": plan.Name.ValueString(),
+		tflog.Trace(ctx, "Started Lima instance", map[string]any{
+			"name": name,
+		})
+	case !plan.Running.ValueBool() && !stopped:
+		tflog.Debug(ctx, "Stopping Lima instance", map[string]any{
+			"name": name,
+		})
+
+		if err := r.providerData.Client.StopInstance(ctx, name); err != nil {
+			timeout.addError(&resp.Diagnostics, "Failed to stop Lima instance", err, instanceErrorAttributes)
+			return
+		}
+
+		tflog.Trace(ctx, "Stopped Lima instance", map[string]any{
+			"name": name,
 		})
 	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	r.providerData.setIdentity(ctx, &resp.Diagnostics, resp.Identity, plan.Name)
 }
 
 func (r *LimaInstanceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, rec := limactl.WithRecorder(ctx)
+	defer addDryRunWarning(&resp.Diagnostics, rec)
+
 	var data LimaInstanceResourceModel
 
 	// Read Terraform prior state data into the model
@@ -620,41 +814,105 @@ func (r *LimaInstanceResource) Delete(ctx context.Context, req resource.DeleteRe
 		return
 	}
 
+	name := r.providerData.limaName(data.Name.ValueString())
+	ctx = limactl.WithOperation(ctx, "lima_instance."+name, limactl.OperationDelete)
+
+	r.providerData.checkIdentity(ctx, &resp.Diagnostics, req.Identity, "instance", data.Name)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := data.Timeouts.Delete(ctx, defaultInstanceDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout := operationTimeout{name: "delete", duration: deleteTimeout}
+	ctx, cancel := timeout.apply(ctx)
+	defer cancel()
+
+	unlock, diags := r.lock(ctx, name, data.Disks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	defer unlock()
+
+	r.providerData.checkOwner(ctx, &resp.Diagnostics, "instance", data.Name.ValueString(), data.ForceDelete.ValueBool())
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	tflog.Debug(ctx, "Deleting Lima instance", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 
 	// Stop and delete the Lima instance
 	// First stop it
-	stopCmd := exec.CommandContext(ctx, "limactl", "stop", data.Name.ValueString())
-	stopOutput, stopErr := stopCmd.CombinedOutput()
-	if stopErr != nil {
+	if err := r.providerData.Client.StopInstance(ctx, name); err != nil {
 		tflog.Warn(ctx, "Failed to stop Lima instance (may already be stopped)", map[string]any{
-			"name":   data.Name.ValueString(),
-			"error":  stopErr.Error(),
-			"output": string(stopOutput),
+			"name":  name,
+			"error": err.Error(),
 		})
 	}
 
 	// Then delete it
-	deleteCmd := exec.CommandContext(ctx, "limactl", "delete", data.Name.ValueString())
-	deleteOutput, deleteErr := deleteCmd.CombinedOutput()
-	if deleteErr != nil {
-		resp.Diagnostics.AddError(
-			"Failed to delete Lima instance",
-			fmt.Sprintf("Command: limactl delete %s\nError: %s\nOutput: %s", data.Name.ValueString(), deleteErr, string(deleteOutput)),
-		)
+	if err := r.providerData.Client.DeleteInstance(ctx, name); err != nil {
+		timeout.addError(&resp.Diagnostics, "Failed to delete Lima instance", err, instanceErrorAttributes)
 		return
 	}
 
 	tflog.Trace(ctx, "Deleted Lima instance", map[string]any{
-		"name": data.Name.ValueString(),
+		"name": name,
 	})
 }
 
+// lock serializes operations on the instance and on the disks it references.
+func (r *LimaInstanceResource) lock(ctx context.Context, name string, disks ...types.List) (func(), diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	keys := []string{instanceLockKey(name)}
+
+	for _, list := range disks {
+		if list.IsNull() || list.IsUnknown() {
+			continue
+		}
+
+		var models []DisksModel
+		diags.Append(list.ElementsAs(ctx, &models, false)...)
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		for _, disk := range models {
+			keys = append(keys, diskLockKey(r.providerData.limaName(disk.Name.ValueString())))
+		}
+	}
+
+	unlock, err := r.providerData.locks.Lock(ctx, keys...)
+	if err != nil {
+		diags.AddError(
+			"Failed to lock Lima instance",
+			fmt.Sprintf("Gave up waiting for other operations on instance %q: %s", name, err),
+		)
+		return nil, diags
+	}
+
+	return unlock, diags
+}
+
+// start boots the instance once one of the max_parallel_operations slots is free.
+func (r *LimaInstanceResource) start(ctx context.Context, name string) error {
+	release, err := r.providerData.locks.AcquireBoot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return r.providerData.Client.StartInstance(ctx, name)
+}
+
 func (r *LimaInstanceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	// Import using the instance name
-	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
-	// Also set the ID
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	r.providerData.importState(ctx, req, resp, "instance")
 }
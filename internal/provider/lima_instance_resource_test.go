@@ -2,9 +2,17 @@ package provider
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
 )
 
 func TestAccLimaInstanceResource(t *testing.T) {
@@ -25,8 +33,6 @@ func TestAccLimaInstanceResource(t *testing.T) {
 				ResourceName:      "lima_instance.test",
 				ImportState:       true,
 				ImportStateVerify: true,
-				// Ignore computed boolean attributes with defaults since they won't be in the imported state
-				ImportStateVerifyIgnore: []string{"mount_inotify", "mount_none", "mount_writable", "plain", "rosetta", "video"},
 			},
 			// Update and Read testing - most changes force replacement
 			{
@@ -105,6 +111,529 @@ func TestAccLimaInstanceResourceWithDisks(t *testing.T) {
 	})
 }
 
+func TestUnitLimaInstanceResource(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		// Importing by identity needs Terraform 1.12
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{tfversion.SkipBelow(tfversion.Version1_12_0)},
+		CheckDestroy:           testCheckFakeInstanceDestroyed(fake, "unit-instance"),
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccLimaInstanceResourceConfigWithResources("unit-instance", 2, 4, 100),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "id", "unit-instance"),
+					resource.TestCheckResourceAttr("lima_instance.test", "cpus", "2"),
+					testCheckFakeInstance(fake, "unit-instance", func(inst *limactl.Instance) error {
+						if inst.Status != limactl.StatusRunning || inst.CPUs != 2 {
+							return fmt.Errorf("expected a running instance with 2 CPUs, got %s with %d", inst.Status, inst.CPUs)
+						}
+						if fake.File(inst.Dir+"/"+limactl.OwnerFile) == nil {
+							return fmt.Errorf("expected an ownership marker in %s", inst.Dir)
+						}
+						return nil
+					}),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:            "lima_instance.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"timeouts"},
+			},
+			// Import with an import block and the resource identity
+			{
+				ResourceName:    "lima_instance.test",
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+			// Update in place
+			{
+				Config: testAccLimaInstanceResourceConfigWithResources("unit-instance", 4, 8, 100),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "cpus", "4"),
+					testCheckFakeInstance(fake, "unit-instance", func(inst *limactl.Instance) error {
+						if inst.Status != limactl.StatusRunning || inst.CPUs != 4 || inst.Memory != 8<<30 {
+							return fmt.Errorf("expected a running instance with 4 CPUs and 8 GiB, got %s with %d and %d bytes", inst.Status, inst.CPUs, inst.Memory)
+						}
+						return nil
+					}),
+				),
+			},
+			// Deleted outside of Terraform
+			{
+				PreConfig:          func() { fake.Remove("unit-instance") },
+				Config:             testAccLimaInstanceResourceConfigWithResources("unit-instance", 4, 8, 100),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceStartFailure(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+	fake.Fail("start", "guest agent does not seem to be running")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccLimaInstanceResourceConfig("unit-broken"),
+				ExpectError: regexp.MustCompile(`guest agent does not seem to be running`),
+			},
+			// The instance that failed to boot was deleted
+			{
+				Config: testAccLimaInstanceResourceConfig("unit-broken"),
+				Check:  resource.TestCheckResourceAttr("lima_instance.test", "id", "unit-broken"),
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceWithDisks(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLimaInstanceResourceConfigWithDisks("unit-disks"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "disks.0.name", "test-data-disk"),
+					func(*terraform.State) error {
+						if disk := fake.Disk("test-data-disk"); disk == nil || disk.Instance != "unit-disks" {
+							return fmt.Errorf("expected test-data-disk to be attached to unit-disks, got %+v", disk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceNamePrefix(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+	config := `
+provider "lima" {
+  name_prefix = "alice-"
+}
+` + testAccLimaInstanceResourceConfigWithDisks("unit-prefix")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		// Importing by identity needs Terraform 1.12
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{tfversion.SkipBelow(tfversion.Version1_12_0)},
+		CheckDestroy:           testCheckFakeInstanceDestroyed(fake, "alice-unit-prefix"),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "name", "unit-prefix"),
+					resource.TestCheckResourceAttr("lima_instance.test", "disks.0.name", "test-data-disk"),
+					resource.TestCheckResourceAttr("lima_disk.data", "name", "test-data-disk"),
+					testCheckFakeInstance(fake, "alice-unit-prefix", func(*limactl.Instance) error { return nil }),
+					func(*terraform.State) error {
+						if disk := fake.Disk("alice-test-data-disk"); disk == nil || disk.Instance != "alice-unit-prefix" {
+							return fmt.Errorf("expected alice-test-data-disk to be attached to alice-unit-prefix, got %+v", disk)
+						}
+						return nil
+					},
+				),
+			},
+			// Importing by the limactl name strips the prefix
+			{
+				Config:                  config,
+				ResourceName:            "lima_instance.test",
+				ImportState:             true,
+				ImportStateId:           "alice-unit-prefix",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"timeouts"},
+			},
+			{
+				Config:          config,
+				ResourceName:    "lima_instance.test",
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceRunning(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	testCheckStatus := func(status string, cpus int) resource.TestCheckFunc {
+		return testCheckFakeInstance(fake, "unit-running", func(inst *limactl.Instance) error {
+			if inst.Status != status || inst.CPUs != cpus {
+				return fmt.Errorf("expected a %s instance with %d CPUs, got %s with %d", status, cpus, inst.Status, inst.CPUs)
+			}
+			return nil
+		})
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		CheckDestroy:             testCheckFakeInstanceDestroyed(fake, "unit-running"),
+		Steps: []resource.TestStep{
+			// Created without starting it
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-running", 4, false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "running", "false"),
+					testCheckStatus(limactl.StatusStopped, 4),
+				),
+			},
+			// Edited, and left stopped
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-running", 2, false),
+				Check:  testCheckStatus(limactl.StatusStopped, 2),
+			},
+			// Started in place
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-running", 2, true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "running", "true"),
+					testCheckStatus(limactl.StatusRunning, 2),
+				),
+			},
+			// Stopped by hand
+			{
+				PreConfig: func() {
+					fake.Update("unit-running", func(inst *limactl.Instance) { inst.Status = limactl.StatusStopped })
+				},
+				Config:             testAccLimaInstanceResourceConfigWithRunning("unit-running", 2, true),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-running", 2, true),
+				Check:  testCheckStatus(limactl.StatusRunning, 2),
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceDrift(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		CheckDestroy:             testCheckFakeInstanceDestroyed(fake, "unit-drift"),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-drift", 2, true),
+			},
+			// Edited by hand
+			{
+				PreConfig: func() {
+					fake.Update("unit-drift", func(inst *limactl.Instance) {
+						cpus := 8
+						inst.CPUs, inst.Config.CPUs = cpus, &cpus
+					})
+				},
+				Config:             testAccLimaInstanceResourceConfigWithRunning("unit-drift", 2, true),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccLimaInstanceResourceConfigWithRunning("unit-drift", 2, true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("lima_instance.test", "cpus", "2"),
+					testCheckFakeInstance(fake, "unit-drift", func(inst *limactl.Instance) error {
+						if inst.CPUs != 2 || inst.Status != limactl.StatusRunning {
+							return fmt.Errorf("expected a running instance with 2 CPUs, got %s with %d", inst.Status, inst.CPUs)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceUpdateRemovals(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		CheckDestroy:             testCheckFakeInstanceDestroyed(fake, "unit-removals"),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLimaInstanceResourceConfigWithOptions("unit-removals", true),
+				Check: testCheckFakeInstance(fake, "unit-removals", func(inst *limactl.Instance) error {
+					if len(inst.Config.DNS) != 1 || len(inst.Config.Mounts) != 3 || len(inst.Config.Networks) != 1 {
+						return fmt.Errorf("expected the dns server, mount and network to be added, got %+v", inst.Config)
+					}
+					return nil
+				}),
+			},
+			// Removed lists and options turned off are applied
+			{
+				Config: testAccLimaInstanceResourceConfigWithOptions("unit-removals", false),
+				Check: testCheckFakeInstance(fake, "unit-removals", func(inst *limactl.Instance) error {
+					cfg := inst.Config
+					if len(cfg.DNS) != 0 || len(cfg.Mounts) != 2 || len(cfg.Networks) != 0 {
+						return fmt.Errorf("expected the dns server, mount and network to be removed, got %+v", cfg)
+					}
+					if *cfg.Video.Display != "none" || *cfg.Rosetta.Enabled {
+						return fmt.Errorf("expected video and rosetta to be off, got %+v", cfg)
+					}
+					return nil
+				}),
+			},
+		},
+	})
+}
+
+func TestUnitLimaInstanceResourceOwnership(t *testing.T) {
+	testUnitPreCheck(t)
+
+	fake := newFakeLimactl()
+
+	// A colleague's instance, created without Terraform
+	fake.instances["unit-colleague"] = &limactl.Instance{
+		Name: "unit-colleague", Dir: "/fake/lima/unit-colleague", Status: limactl.StatusRunning,
+		VMType: "qemu", Arch: "x86_64", CPUs: 4, Memory: 4 << 30, Disk: 100 << 30, Config: &limactl.InstanceConfig{},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fakeProtoV6ProviderFactories(fake),
+		CheckDestroy:             testCheckFakeInstanceDestroyed(fake, "unit-colleague"),
+		Steps: []resource.TestStep{
+			{
+				Config:             testAccLimaInstanceResourceConfig("unit-colleague"),
+				ResourceName:       "lima_instance.test",
+				ImportState:        true,
+				ImportStateId:      "unit-colleague",
+				ImportStatePersist: true,
+			},
+			{
+				Config:      testAccLimaInstanceResourceConfig("unit-colleague"),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile(`not owned by this workspace`),
+			},
+			{
+				Config: testAccLimaInstanceResourceConfigWithForceDelete("unit-colleague"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("lima_instance.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("lima_instance.test", "force_delete", "true"),
+			},
+		},
+	})
+}
+
+func TestLimaInstanceResourceImportPlan(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	config := h.config("lima_instance", map[string]tftypes.Value{
+		"name":   tftypes.NewValue(tftypes.String, "dev"),
+		"cpus":   tftypes.NewValue(tftypes.Number, 2),
+		"memory": tftypes.NewValue(tftypes.Number, 4),
+		"disk":   tftypes.NewValue(tftypes.Number, 100),
+	})
+	created := h.create("lima_instance", config)
+
+	if changes := h.plan(created, config).changes(); len(changes) > 0 {
+		t.Errorf("expected no changes after create, got %v", changes)
+	}
+
+	imported := h.importResource("lima_instance", "dev")
+	checkSameState(t, created.state, imported.state, "timeouts")
+
+	if changes := h.plan(imported, config).changes(); len(changes) > 0 {
+		t.Errorf("expected no changes after import, got %v", changes)
+	}
+
+	imported = h.importIdentity("lima_instance", created.identity)
+	checkSameState(t, created.state, imported.state, "timeouts")
+
+	if changes := h.plan(imported, config).changes(); len(changes) > 0 {
+		t.Errorf("expected no changes after import by identity, got %v", changes)
+	}
+}
+
+func TestLimaInstanceResourceImportPlanCreateSettings(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, map[string]tftypes.Value{
+		"name_prefix": tftypes.NewValue(tftypes.String, "alice-"),
+	})
+
+	h.create("lima_disk", h.config("lima_disk", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "data"),
+		"size": tftypes.NewValue(tftypes.Number, 10),
+	}))
+
+	disksType := h.schema("lima_instance").ValueType().(tftypes.Object).AttributeTypes["disks"].(tftypes.List)
+	diskType := disksType.ElementType.(tftypes.Object)
+	config := h.config("lima_instance", map[string]tftypes.Value{
+		"name":       tftypes.NewValue(tftypes.String, "dev"),
+		"template":   tftypes.NewValue(tftypes.String, "docker"),
+		"mount_none": tftypes.NewValue(tftypes.Bool, true),
+		"disks": tftypes.NewValue(disksType, []tftypes.Value{
+			tftypes.NewValue(diskType, map[string]tftypes.Value{
+				"name":        tftypes.NewValue(tftypes.String, "data"),
+				"mount_point": tftypes.NewValue(tftypes.String, "/mnt/data"),
+			}),
+		}),
+	})
+	created := h.create("lima_instance", config)
+
+	// The template and mount_none are read back from the ownership marker
+	imported := h.importResource("lima_instance", "alice-dev")
+	checkSameState(t, created.state, imported.state, "timeouts")
+
+	plan := h.plan(imported, config)
+	if changes := plan.changes(); len(changes) > 0 || len(plan.RequiresReplace) > 0 {
+		t.Errorf("expected no changes after import, got %v and replacement for %v", changes, plan.replaced())
+	}
+
+	// The identity holds the configured name, without the prefix
+	imported = h.importIdentity("lima_instance", created.identity)
+	checkSameState(t, created.state, imported.state, "timeouts")
+
+	plan = h.plan(imported, config)
+	if changes := plan.changes(); len(changes) > 0 || len(plan.RequiresReplace) > 0 {
+		t.Errorf("expected no changes after import by identity, got %v and replacement for %v", changes, plan.replaced())
+	}
+}
+
+func TestLimaInstanceResourceImportPlanUnmanaged(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	// Created without Terraform from a template with rosetta, video and a
+	// network
+	h.create("lima_instance", h.config("lima_instance", map[string]tftypes.Value{
+		"name":    tftypes.NewValue(tftypes.String, "colleague"),
+		"rosetta": tftypes.NewValue(tftypes.Bool, true),
+		"video":   tftypes.NewValue(tftypes.Bool, true),
+		"network": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{tftypes.NewValue(tftypes.String, "vzNAT")}),
+	}))
+	fake.removeFiles(fake.Instance("colleague").Dir)
+
+	res := h.importResource("lima_instance", "colleague")
+
+	config := h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "colleague"),
+		"cpus": tftypes.NewValue(tftypes.Number, 4),
+	})
+	plan := h.plan(res, config)
+	if changes := plan.changes(); len(changes) > 0 || len(plan.RequiresReplace) > 0 {
+		t.Errorf("expected no changes after import, got %v and replacement for %v", changes, plan.replaced())
+	}
+
+	config = h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "colleague"),
+		"cpus": tftypes.NewValue(tftypes.Number, 2),
+	})
+	plan = h.plan(res, config)
+	if changes := plan.changes(); !slices.Equal(changes, []string{"cpus"}) {
+		t.Errorf("expected only cpus to change, got %v", changes)
+	}
+
+	h.applyPlan(res, plan)
+
+	edits := slices.DeleteFunc(fake.Commands(), func(args []string) bool { return args[0] != "edit" })
+	if len(edits) != 1 || !slices.Equal(edits[0], []string{"edit", "colleague", "--cpus=2"}) {
+		t.Errorf("expected only the cpus to be edited, got %v", edits)
+	}
+}
+
+func TestLimaInstanceResourceUpdateRemovedAttributes(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	stringList := tftypes.List{ElementType: tftypes.String}
+	res := h.create("lima_instance", h.config("lima_instance", map[string]tftypes.Value{
+		"name":           tftypes.NewValue(tftypes.String, "dev"),
+		"cpus":           tftypes.NewValue(tftypes.Number, 2),
+		"mount_type":     tftypes.NewValue(tftypes.String, "9p"),
+		"mount":          tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "/data")}),
+		"mount_writable": tftypes.NewValue(tftypes.Bool, true),
+	}))
+
+	mountPoint := "/mnt/tmp"
+	fake.Update("dev", func(inst *limactl.Instance) { inst.Config.Mounts[1].MountPoint = &mountPoint })
+
+	// Turning mount_writable off applies to the template mounts too, and
+	// keeps the mounts in place
+	h.apply(res, h.config("lima_instance", map[string]tftypes.Value{
+		"name":       tftypes.NewValue(tftypes.String, "dev"),
+		"cpus":       tftypes.NewValue(tftypes.Number, 2),
+		"mount_type": tftypes.NewValue(tftypes.String, "9p"),
+		"mount":      tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "/data:w")}),
+	}))
+
+	cfg := fake.Instance("dev").Config
+	if len(cfg.Mounts) != 3 || boolValue(cfg.Mounts[0].Writable) || boolValue(cfg.Mounts[1].Writable) || !boolValue(cfg.Mounts[2].Writable) {
+		t.Errorf("expected the template mounts to be read-only and /data to be writable, got %+v", cfg.Mounts)
+	}
+	if cfg.Mounts[1].MountPoint == nil || *cfg.Mounts[1].MountPoint != mountPoint {
+		t.Errorf("expected the mount point of the template mount to be kept, got %+v", cfg.Mounts[1])
+	}
+
+	// Removed attributes go back to the template
+	config := h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "dev"),
+	})
+	h.apply(res, config)
+
+	inst := fake.Instance("dev")
+	if inst.CPUs != 4 || inst.Config.CPUs != nil || inst.Config.MountType != nil || len(inst.Config.Mounts) != 2 {
+		t.Errorf("expected the template resources and mounts, got %d CPUs and %+v", inst.CPUs, inst.Config)
+	}
+
+	if changes := h.plan(res, config).changes(); len(changes) > 0 {
+		t.Errorf("expected no changes after removing the attributes, got %v", changes)
+	}
+}
+
+// testCheckFakeInstance runs check against the named instance of fake.
+func testCheckFakeInstance(fake *fakeLimactl, name string, check func(*limactl.Instance) error) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		inst := fake.Instance(name)
+		if inst == nil {
+			return fmt.Errorf("instance %q does not exist", name)
+		}
+
+		return check(inst)
+	}
+}
+
+// testCheckFakeInstanceDestroyed verifies that the named instance was deleted.
+func testCheckFakeInstanceDestroyed(fake *fakeLimactl, name string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if fake.Instance(name) != nil {
+			return fmt.Errorf("instance %q still exists", name)
+		}
+
+		return nil
+	}
+}
+
 func testAccLimaInstanceResourceConfig(name string) string {
 	return fmt.Sprintf(`
 resource "lima_instance" "test" {
@@ -113,6 +642,46 @@ resource "lima_instance" "test" {
 `, name)
 }
 
+func testAccLimaInstanceResourceConfigWithRunning(name string, cpus int, running bool) string {
+	return fmt.Sprintf(`
+resource "lima_instance" "test" {
+  name    = %[1]q
+  cpus    = %[2]d
+  running = %[3]t
+}
+`, name, cpus, running)
+}
+
+func testAccLimaInstanceResourceConfigWithOptions(name string, enabled bool) string {
+	if !enabled {
+		return fmt.Sprintf(`
+resource "lima_instance" "test" {
+  name = %[1]q
+}
+`, name)
+	}
+
+	return fmt.Sprintf(`
+resource "lima_instance" "test" {
+  name    = %[1]q
+  dns     = ["1.1.1.1"]
+  mount   = ["~/src:w"]
+  network = ["vzNAT"]
+  rosetta = true
+  video   = true
+}
+`, name)
+}
+
+func testAccLimaInstanceResourceConfigWithForceDelete(name string) string {
+	return fmt.Sprintf(`
+resource "lima_instance" "test" {
+  name         = %[1]q
+  force_delete = true
+}
+`, name)
+}
+
 func testAccLimaInstanceResourceConfigWithCpus(name string, cpus int) string {
 	return fmt.Sprintf(`
 resource "lima_instance" "test" {
@@ -161,3 +730,68 @@ resource "lima_instance" "test" {
 }
 `, name)
 }
+
+func TestLimaInstanceResourceOwnershipForceDelete(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	// Created by a colleague, without an ownership marker
+	h.create("lima_instance", h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "colleague"),
+	}))
+	fake.removeFiles(fake.Instance("colleague").Dir)
+
+	res := h.importResource("lima_instance", "colleague")
+
+	diags := h.destroy(res)
+	if err := diagnosticsError(diags); err == nil || !strings.Contains(err.Error(), "not owned by this workspace") {
+		t.Fatalf("expected the destroy to be refused, got %v", err)
+	}
+	if fake.Instance("colleague") == nil {
+		t.Fatal("expected the instance to be kept")
+	}
+
+	config := h.config("lima_instance", map[string]tftypes.Value{
+		"name":         tftypes.NewValue(tftypes.String, "colleague"),
+		"force_delete": tftypes.NewValue(tftypes.Bool, true),
+	})
+	plan := h.plan(res, config)
+	if changes := plan.changes(); !slices.Equal(changes, []string{"force_delete"}) || len(plan.RequiresReplace) > 0 {
+		t.Errorf("expected only force_delete to change in place, got %v and replacement for %v", changes, plan.replaced())
+	}
+
+	commands := len(fake.Commands())
+	h.applyPlan(res, plan)
+	for _, args := range fake.Commands()[commands:] {
+		if args[0] != "list" {
+			t.Errorf("expected force_delete not to touch the instance, got %v", args)
+		}
+	}
+
+	if err := diagnosticsError(h.destroy(res)); err != nil {
+		t.Fatalf("expected the destroy to succeed with force_delete, got %v", err)
+	}
+	if fake.Instance("colleague") != nil {
+		t.Error("expected the instance to be deleted")
+	}
+}
+
+func TestLimaInstanceResourceUpdateMountWritableTemplate(t *testing.T) {
+	fake := newFakeLimactl()
+	h := newProtocolHarness(t, fake, nil)
+
+	res := h.create("lima_instance", h.config("lima_instance", map[string]tftypes.Value{
+		"name":           tftypes.NewValue(tftypes.String, "dev"),
+		"mount_writable": tftypes.NewValue(tftypes.Bool, true),
+	}))
+
+	// The mounts are those of the template, which mount is not configured for
+	h.apply(res, h.config("lima_instance", map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "dev"),
+	}))
+
+	cfg := fake.Instance("dev").Config
+	if len(cfg.Mounts) != 2 || boolValue(cfg.Mounts[0].Writable) || boolValue(cfg.Mounts[1].Writable) {
+		t.Errorf("expected the template mounts to be read-only, got %+v", cfg.Mounts)
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// lockManager serializes operations on the same Lima instance or disk and
+// caps how many instances boot at once. Terraform runs Create, Update and
+// Delete of independent resources in parallel, so an instance edit and a
+// resize of a disk attached to it could otherwise interleave.
+type lockManager struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+	boots chan struct{}
+}
+
+func newLockManager(maxParallelBoots int) *lockManager {
+	return &lockManager{
+		locks: map[string]chan struct{}{},
+		boots: make(chan struct{}, maxParallelBoots),
+	}
+}
+
+func instanceLockKey(name string) string {
+	return "instance/" + name
+}
+
+func diskLockKey(name string) string {
+	return "disk/" + name
+}
+
+// Lock acquires the locks for all keys and returns a function releasing them.
+// Keys are taken in sorted order so that overlapping callers cannot deadlock.
+func (m *lockManager) Lock(ctx context.Context, keys ...string) (func(), error) {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	var held []chan struct{}
+	unlock := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			<-held[i]
+		}
+	}
+
+	for _, key := range keys {
+		lock := m.lock(key)
+
+		select {
+		case lock <- struct{}{}:
+			held = append(held, lock)
+		case <-ctx.Done():
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+
+	return unlock, nil
+}
+
+// AcquireBoot waits for a free boot slot and returns a function releasing it.
+func (m *lockManager) AcquireBoot(ctx context.Context) (func(), error) {
+	select {
+	case m.boots <- struct{}{}:
+		return func() { <-m.boots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *lockManager) lock(key string) chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		m.locks[key] = lock
+	}
+
+	return lock
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockManagerSerializesOverlappingKeys(t *testing.T) {
+	m := newLockManager(1)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		// Alternate the key order to make sure sorting prevents deadlocks.
+		keys := []string{instanceLockKey("dev"), diskLockKey("data")}
+		if i%2 == 1 {
+			keys = []string{diskLockKey("data"), instanceLockKey("dev")}
+		}
+
+		go func() {
+			defer wg.Done()
+
+			unlock, err := m.Lock(context.Background(), keys...)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+
+	wg.Wait()
+
+	if maxRunning.Load() != 1 {
+		t.Errorf("expected operations to be serialized, got %d concurrent", maxRunning.Load())
+	}
+}
+
+func TestLockManagerIndependentKeys(t *testing.T) {
+	m := newLockManager(1)
+
+	unlock, err := m.Lock(context.Background(), instanceLockKey("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	unlockB, err := m.Lock(ctx, instanceLockKey("b"))
+	if err != nil {
+		t.Fatalf("independent key should not block: %s", err)
+	}
+	unlockB()
+}
+
+func TestLockManagerContextCancel(t *testing.T) {
+	m := newLockManager(1)
+
+	unlock, err := m.Lock(context.Background(), diskLockKey("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.Lock(ctx, instanceLockKey("dev"), diskLockKey("data")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// The instance lock taken before blocking must have been released.
+	unlockDev, err := m.Lock(context.Background(), instanceLockKey("dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockDev()
+	unlock()
+}
+
+func TestLockManagerBootSlots(t *testing.T) {
+	m := newLockManager(2)
+
+	release1, _ := m.AcquireBoot(context.Background())
+	release2, _ := m.AcquireBoot(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.AcquireBoot(ctx); err == nil {
+		t.Fatal("expected third boot to wait for a free slot")
+	}
+
+	release1()
+
+	release3, err := m.AcquireBoot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release2()
+	release3()
+}
@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"regexp"
+	"strings"
+)
+
+// namePrefixPattern matches the name_prefix values that keep Lima instance
+// and disk names valid.
+var namePrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// limaName returns the limactl name of the instance or disk configured as
+// name, with the provider name_prefix added.
+func (d *LimaProviderData) limaName(name string) string {
+	return d.namePrefix + name
+}
+
+// configName returns the configured name of the instance or disk that limactl
+// knows as name. It reports false when name lacks the provider name_prefix,
+// so the object belongs to another namespace.
+func (d *LimaProviderData) configName(name string) (string, bool) {
+	return strings.CutPrefix(name, d.namePrefix)
+}
@@ -0,0 +1,38 @@
+package provider
+
+import "testing"
+
+func TestNamePrefix(t *testing.T) {
+	d := &LimaProviderData{namePrefix: "alice-"}
+
+	if got := d.limaName("dev"); got != "alice-dev" {
+		t.Errorf("limaName(dev) = %q, want alice-dev", got)
+	}
+
+	if got, ok := d.configName("alice-dev"); !ok || got != "dev" {
+		t.Errorf("configName(alice-dev) = %q, %t, want dev, true", got, ok)
+	}
+
+	if _, ok := d.configName("bob-dev"); ok {
+		t.Error("expected bob-dev to be outside the namespace")
+	}
+
+	unprefixed := &LimaProviderData{}
+	if got, ok := unprefixed.configName("bob-dev"); !ok || got != "bob-dev" {
+		t.Errorf("configName(bob-dev) without a prefix = %q, %t, want bob-dev, true", got, ok)
+	}
+}
+
+func TestNamePrefixPattern(t *testing.T) {
+	for prefix, valid := range map[string]bool{
+		"alice-":  true,
+		"ci.42_":  true,
+		"-alice":  false,
+		"al ice-": false,
+		"alice/":  false,
+	} {
+		if got := namePrefixPattern.MatchString(prefix); got != valid {
+			t.Errorf("namePrefixPattern.MatchString(%q) = %t, want %t", prefix, got, valid)
+		}
+	}
+}
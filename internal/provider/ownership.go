@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// workspaceEnvVar selects the Terraform workspace when it is exported. The
+// default workspace includes it, so that the workspaces of a configuration
+// selected this way are told apart.
+const workspaceEnvVar = "TF_WORKSPACE"
+
+// defaultWorkspace returns the workspace recorded when none is configured: the
+// user and the working directory of the provider, which Terraform runs in the
+// root module, so that the configurations of different users or directories
+// do not own each other's instances and disks. It returns "", which owns
+// nothing, when they cannot be determined.
+func defaultWorkspace() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	workspace := u.Username + "@" + dir
+	if tfWorkspace := os.Getenv(workspaceEnvVar); tfWorkspace != "" {
+		workspace += ":" + tfWorkspace
+	}
+
+	return workspace
+}
+
+// objectDir returns the directory of the instance or disk configured as name.
+func (d *LimaProviderData) objectDir(ctx context.Context, kind string, name string) (string, error) {
+	var dir string
+
+	if kind == "disk" {
+		disk, err := d.Client.GetDisk(ctx, d.limaName(name))
+		if err != nil {
+			return "", err
+		}
+		dir = disk.Dir
+	} else {
+		inst, err := d.Client.GetInstance(ctx, d.limaName(name))
+		if err != nil {
+			return "", err
+		}
+		dir = inst.Dir
+	}
+
+	if dir == "" {
+		return "", fmt.Errorf("limactl did not report the directory of %s %q", kind, d.limaName(name))
+	}
+
+	return dir, nil
+}
+
+// writeOwner records the workspace as the owner of a newly created instance
+// or disk, along with the settings in owner. A failure is only a warning: the
+// object exists and deleting it later merely needs force_delete.
+func (d *LimaProviderData) writeOwner(ctx context.Context, diags *diag.Diagnostics, kind string, name string, owner limactl.Owner) {
+	if d.Client.DryRun() {
+		return
+	}
+
+	owner.Workspace = d.workspace
+	owner.Resource = "lima_" + kind
+	owner.Name = name
+	owner.Address = owner.Resource + "." + name
+	owner.CreatedAt = time.Now().UTC()
+
+	dir, err := d.objectDir(ctx, kind, name)
+	if err == nil {
+		err = d.Client.WriteOwner(ctx, dir, owner)
+	}
+	if err != nil {
+		diags.AddWarning(
+			"Failed to write ownership marker",
+			fmt.Sprintf("The %s %q was created, but recording workspace %q as its owner failed. Deleting it will require force_delete.\n\n%s",
+				kind, d.limaName(name), d.workspace, err),
+		)
+	}
+}
+
+// checkOwner reports an error on force_delete unless the instance or disk
+// configured as name was created by this workspace, so that an object
+// imported over someone else's is not deleted. Objects that no longer exist
+// pass, deleting them is left to limactl.
+func (d *LimaProviderData) checkOwner(ctx context.Context, diags *diag.Diagnostics, kind string, name string, force bool) {
+	dir, err := d.objectDir(ctx, kind, name)
+	if errors.Is(err, limactl.ErrNotFound) {
+		return
+	}
+
+	var owner *limactl.Owner
+	if err == nil {
+		owner, err = d.Client.ReadOwner(ctx, dir)
+	}
+	if err != nil {
+		diags.AddError(
+			"Failed to read ownership marker",
+			fmt.Sprintf("Could not check which workspace owns the %s %q: %s", kind, d.limaName(name), err),
+		)
+		return
+	}
+
+	if owner != nil && d.workspace != "" && owner.Workspace == d.workspace {
+		return
+	}
+
+	if force {
+		tflog.Warn(ctx, "Deleting Lima object not owned by this workspace", map[string]any{
+			"kind":      kind,
+			"name":      d.limaName(name),
+			"owner":     describeOwner(owner),
+			"workspace": d.workspace,
+		})
+		return
+	}
+
+	diags.AddAttributeError(
+		path.Root("force_delete"),
+		fmt.Sprintf("Lima %s not owned by this workspace", kind),
+		fmt.Sprintf("Refusing to delete the %s %q: it %s, but this is workspace %q. "+
+			"It was probably imported, or created before ownership markers were written. "+
+			"To delete it anyway, set force_delete = true and apply before destroying it, or remove it from state with `terraform state rm` to keep it.",
+			kind, d.limaName(name), describeOwner(owner), d.workspace),
+	)
+}
+
+// reportOwner adds a warning describing the ownership of an imported instance
+// or disk.
+func (d *LimaProviderData) reportOwner(ctx context.Context, diags *diag.Diagnostics, kind string, name string) {
+	dir, err := d.objectDir(ctx, kind, name)
+	if errors.Is(err, limactl.ErrNotFound) {
+		return
+	}
+
+	var owner *limactl.Owner
+	if err == nil {
+		owner, err = d.Client.ReadOwner(ctx, dir)
+	}
+	if err != nil {
+		diags.AddWarning(
+			"Failed to read ownership marker",
+			fmt.Sprintf("Could not check which workspace owns the %s %q: %s", kind, d.limaName(name), err),
+		)
+		return
+	}
+
+	detail := fmt.Sprintf("The imported %s %q %s.", kind, d.limaName(name), describeOwner(owner))
+	if owner == nil || owner.Workspace != d.workspace {
+		detail += fmt.Sprintf(" Terraform will refuse to delete it from workspace %q unless force_delete is set.", d.workspace)
+	}
+
+	diags.AddWarning(fmt.Sprintf("Imported Lima %s ownership", kind), detail)
+}
+
+// describeOwner describes an ownership marker for diagnostics.
+func describeOwner(owner *limactl.Owner) string {
+	if owner == nil {
+		return "has no ownership marker"
+	}
+
+	// Markers written before the address was recorded
+	address := owner.Address
+	if address == "" {
+		address = fmt.Sprintf("%s %q", owner.Resource, owner.Name)
+	}
+
+	return fmt.Sprintf("was created by workspace %q as %s on %s",
+		owner.Workspace, address, owner.CreatedAt.Format(time.RFC3339))
+}
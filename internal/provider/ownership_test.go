@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func TestOwnership(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeLimactl()
+	fake.instances["alice-dev"] = &limactl.Instance{Name: "alice-dev", Dir: "/fake/lima/alice-dev"}
+	fake.instances["alice-other"] = &limactl.Instance{Name: "alice-other", Dir: "/fake/lima/alice-other"}
+	fake.disks["alice-data"] = &limactl.Disk{Name: "alice-data", Dir: "/fake/lima/_disks/alice-data"}
+
+	client := limactl.New(limactl.Config{Transport: fake})
+	d := &LimaProviderData{Client: client, namePrefix: "alice-", workspace: "infra/default"}
+	other := &LimaProviderData{Client: client, namePrefix: "alice-", workspace: "infra/staging"}
+
+	var diags diag.Diagnostics
+	d.writeOwner(ctx, &diags, "instance", "dev", limactl.Owner{})
+	d.writeOwner(ctx, &diags, "disk", "data", limactl.Owner{})
+	other.writeOwner(ctx, &diags, "instance", "other", limactl.Owner{})
+	if diags.HasError() || diags.WarningsCount() > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	marker := fake.File("/fake/lima/alice-dev/" + limactl.OwnerFile)
+	if !strings.Contains(string(marker), `"workspace": "infra/default"`) || !strings.Contains(string(marker), `"address": "lima_instance.dev"`) {
+		t.Errorf("unexpected marker %s", marker)
+	}
+
+	fake.instances["bob-dev"] = &limactl.Instance{Name: "bob-dev", Dir: "/fake/lima/bob-dev"}
+	unprefixed := &LimaProviderData{Client: client, workspace: "infra/default"}
+
+	// Without a workspace, even the objects it created are not owned
+	fake.instances["unknown"] = &limactl.Instance{Name: "unknown", Dir: "/fake/lima/unknown"}
+	unknown := &LimaProviderData{Client: client}
+	unknown.writeOwner(ctx, &diags, "instance", "unknown", limactl.Owner{})
+
+	tests := map[string]struct {
+		d       *LimaProviderData
+		kind    string
+		name    string
+		force   bool
+		refused bool
+	}{
+		"owned instance":      {d: d, kind: "instance", name: "dev"},
+		"owned disk":          {d: d, kind: "disk", name: "data"},
+		"other workspace":     {d: d, kind: "instance", name: "other", refused: true},
+		"forced":              {d: d, kind: "instance", name: "other", force: true},
+		"no marker":           {d: unprefixed, kind: "instance", name: "bob-dev", refused: true},
+		"no marker forced":    {d: unprefixed, kind: "instance", name: "bob-dev", force: true},
+		"deleted out of band": {d: d, kind: "instance", name: "gone"},
+		"unknown workspace":   {d: unknown, kind: "instance", name: "unknown", refused: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			tt.d.checkOwner(ctx, &diags, tt.kind, tt.name, tt.force)
+
+			if diags.HasError() != tt.refused {
+				t.Fatalf("expected refused %t, got %v", tt.refused, diags)
+			}
+
+			if tt.refused {
+				withPath, ok := diags[0].(diag.DiagnosticWithPath)
+				if !ok || !withPath.Path().Equal(path.Root("force_delete")) {
+					t.Errorf("expected the error on force_delete, got %v", diags[0])
+				}
+			}
+		})
+	}
+
+	t.Run("report", func(t *testing.T) {
+		var diags diag.Diagnostics
+		d.reportOwner(ctx, &diags, "instance", "other")
+
+		if diags.WarningsCount() != 1 || !strings.Contains(diags[0].Detail(), `workspace "infra/staging" as lima_instance.other`) || !strings.Contains(diags[0].Detail(), "force_delete") {
+			t.Errorf("expected a warning naming the owner, got %v", diags)
+		}
+	})
+}
+
+func TestDefaultWorkspace(t *testing.T) {
+	t.Setenv(workspaceEnvVar, "")
+
+	t.Chdir(t.TempDir())
+	first := defaultWorkspace()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	second := defaultWorkspace()
+
+	if first == "" || first == second || !strings.HasSuffix(second, filepath.Base(dir)) {
+		t.Errorf("expected workspaces naming the directories, got %q and %q", first, second)
+	}
+
+	t.Setenv(workspaceEnvVar, "staging")
+	if got := defaultWorkspace(); got != second+":staging" {
+		t.Errorf("expected the Terraform workspace to be appended, got %q", got)
+	}
+}
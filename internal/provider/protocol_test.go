@@ -0,0 +1,464 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// protocolHarness drives the provider server the way Terraform does, so that
+// plans can be checked without the Terraform CLI. It proposes new states from
+// the configuration and the prior state, checks that plans and applies are
+// consistent, and carries private state and identities between calls.
+type protocolHarness struct {
+	t      *testing.T
+	ctx    context.Context
+	server tfprotov6.ProviderServer
+
+	schemas map[string]*tfprotov6.Schema
+}
+
+// protocolResource is a resource instance as Terraform keeps it in state.
+type protocolResource struct {
+	typeName string
+	state    tftypes.Value
+	private  []byte
+	identity *tfprotov6.ResourceIdentityData
+}
+
+// protocolPlan is a planned change of a resource.
+type protocolPlan struct {
+	*tfprotov6.PlanResourceChangeResponse
+
+	prior, config, planned tftypes.Value
+}
+
+// newProtocolHarness returns a harness for a provider that runs against fake
+// and is configured with the provider attributes in config.
+func newProtocolHarness(t *testing.T, fake *fakeLimactl, config map[string]tftypes.Value) *protocolHarness {
+	t.Helper()
+
+	h := &protocolHarness{
+		t:      t,
+		ctx:    context.Background(),
+		server: providerserver.NewProtocol6(newFakeProvider(fake)())(),
+	}
+
+	schemaResp, err := h.server.GetProviderSchema(h.ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.checkDiagnostics("get provider schema", schemaResp.Diagnostics)
+	h.schemas = schemaResp.ResourceSchemas
+
+	providerConfig := blockValue(schemaResp.Provider.Block, config)
+	configureResp, err := h.server.ConfigureProvider(h.ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: "1.12.0",
+		Config:           h.dynamicValue(providerConfig),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.checkDiagnostics("configure provider", configureResp.Diagnostics)
+
+	return h
+}
+
+// config returns the configuration of a typeName resource with the attributes
+// and blocks in values, leaving the others unset.
+func (h *protocolHarness) config(typeName string, values map[string]tftypes.Value) tftypes.Value {
+	return blockValue(h.schema(typeName).Block, values)
+}
+
+// create plans and applies a new typeName resource.
+func (h *protocolHarness) create(typeName string, config tftypes.Value) *protocolResource {
+	h.t.Helper()
+
+	res := &protocolResource{
+		typeName: typeName,
+		state:    tftypes.NewValue(h.schema(typeName).ValueType(), nil),
+	}
+	h.apply(res, config)
+
+	return res
+}
+
+// importResource imports the typeName resource with id and refreshes it, as
+// an import block does.
+func (h *protocolHarness) importResource(typeName string, id string) *protocolResource {
+	h.t.Helper()
+
+	return h.importRequest(&tfprotov6.ImportResourceStateRequest{
+		TypeName: typeName,
+		ID:       id,
+	})
+}
+
+// importIdentity imports the typeName resource with the identity of another
+// resource and refreshes it, as an import block with an identity does.
+func (h *protocolHarness) importIdentity(typeName string, identity *tfprotov6.ResourceIdentityData) *protocolResource {
+	h.t.Helper()
+
+	if identity == nil {
+		h.t.Fatalf("expected %s to have an identity", typeName)
+	}
+
+	return h.importRequest(&tfprotov6.ImportResourceStateRequest{
+		TypeName: typeName,
+		Identity: identity,
+	})
+}
+
+func (h *protocolHarness) importRequest(req *tfprotov6.ImportResourceStateRequest) *protocolResource {
+	h.t.Helper()
+
+	typeName := req.TypeName
+	resp, err := h.server.ImportResourceState(h.ctx, req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.checkDiagnostics("import "+typeName, resp.Diagnostics)
+
+	if len(resp.ImportedResources) != 1 {
+		h.t.Fatalf("expected one imported resource, got %d", len(resp.ImportedResources))
+	}
+	imported := resp.ImportedResources[0]
+
+	res := &protocolResource{
+		typeName: typeName,
+		state:    h.value(typeName, imported.State),
+		private:  imported.Private,
+		identity: imported.Identity,
+	}
+	h.refresh(res)
+
+	return res
+}
+
+// refresh reads res, as terraform refresh does.
+func (h *protocolHarness) refresh(res *protocolResource) {
+	h.t.Helper()
+
+	resp, err := h.server.ReadResource(h.ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:        res.typeName,
+		CurrentState:    h.dynamicValue(res.state),
+		Private:         res.private,
+		CurrentIdentity: res.identity,
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.checkDiagnostics("read "+res.typeName, resp.Diagnostics)
+
+	res.state = h.value(res.typeName, resp.NewState)
+	res.private = resp.Private
+	if resp.NewIdentity != nil {
+		res.identity = resp.NewIdentity
+	}
+}
+
+// plan plans the change of res to config, or its destruction when config is
+// null.
+func (h *protocolHarness) plan(res *protocolResource, config tftypes.Value) *protocolPlan {
+	h.t.Helper()
+
+	schema := h.schema(res.typeName)
+	proposed := proposedNewState(schema.Block, res.state, config)
+
+	resp, err := h.server.PlanResourceChange(h.ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         res.typeName,
+		PriorState:       h.dynamicValue(res.state),
+		ProposedNewState: h.dynamicValue(proposed),
+		Config:           h.dynamicValue(config),
+		PriorPrivate:     res.private,
+		PriorIdentity:    res.identity,
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.checkDiagnostics("plan "+res.typeName, resp.Diagnostics)
+
+	plan := &protocolPlan{
+		PlanResourceChangeResponse: resp,
+		prior:                      res.state,
+		config:                     config,
+		planned:                    h.value(res.typeName, resp.PlannedState),
+	}
+
+	// Terraform rejects plans that do not match the configuration
+	for _, name := range attributeNames(schema.Block) {
+		configured, planned := attributeValue(config, name), attributeValue(plan.planned, name)
+		if !configured.IsNull() && configured.IsFullyKnown() && !configured.Equal(planned) {
+			h.t.Fatalf("planned %s = %s does not match the configured %s", name, planned, configured)
+		}
+	}
+
+	return plan
+}
+
+// apply plans and applies the change of res to config, failing on
+// replacements, and refreshes it afterwards.
+func (h *protocolHarness) apply(res *protocolResource, config tftypes.Value) {
+	h.t.Helper()
+
+	plan := h.plan(res, config)
+	if !res.state.IsNull() && len(plan.RequiresReplace) > 0 {
+		h.t.Fatalf("unexpected replacement for %v", plan.replaced())
+	}
+
+	h.applyPlan(res, plan)
+	if !res.state.IsNull() {
+		h.refresh(res)
+	}
+}
+
+// applyPlan applies plan to res.
+func (h *protocolHarness) applyPlan(res *protocolResource, plan *protocolPlan) {
+	h.t.Helper()
+
+	resp, err := h.server.ApplyResourceChange(h.ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:        res.typeName,
+		PriorState:      h.dynamicValue(plan.prior),
+		PlannedState:    plan.PlannedState,
+		Config:          h.dynamicValue(plan.config),
+		PlannedPrivate:  plan.PlannedPrivate,
+		PlannedIdentity: plan.PlannedIdentity,
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.checkDiagnostics("apply "+res.typeName, resp.Diagnostics)
+
+	newState := h.value(res.typeName, resp.NewState)
+
+	// Terraform rejects results that do not match the known planned values
+	for _, name := range attributeNames(h.schema(res.typeName).Block) {
+		planned, actual := attributeValue(plan.planned, name), attributeValue(newState, name)
+		if planned.IsFullyKnown() && !planned.Equal(actual) {
+			h.t.Fatalf("applied %s = %s does not match the planned %s", name, actual, planned)
+		}
+	}
+
+	res.state = newState
+	res.private = resp.Private
+	res.identity = resp.NewIdentity
+}
+
+// destroy plans and applies the destruction of res.
+func (h *protocolHarness) destroy(res *protocolResource) []*tfprotov6.Diagnostic {
+	h.t.Helper()
+
+	null := tftypes.NewValue(h.schema(res.typeName).ValueType(), nil)
+	plan := h.plan(res, null)
+
+	resp, err := h.server.ApplyResourceChange(h.ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       res.typeName,
+		PriorState:     h.dynamicValue(res.state),
+		PlannedState:   plan.PlannedState,
+		Config:         h.dynamicValue(null),
+		PlannedPrivate: plan.PlannedPrivate,
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	return resp.Diagnostics
+}
+
+// changes returns the attributes that plan changes, such as "cpus", and those
+// that are unknown until applied, such as "memory (known after apply)".
+func (p *protocolPlan) changes() []string {
+	var changes []string
+
+	priorAttrs, plannedAttrs := map[string]tftypes.Value{}, map[string]tftypes.Value{}
+	if !p.prior.IsNull() {
+		_ = p.prior.As(&priorAttrs)
+	}
+	_ = p.planned.As(&plannedAttrs)
+
+	for name, planned := range plannedAttrs {
+		prior, ok := priorAttrs[name]
+		switch {
+		case !planned.IsFullyKnown():
+			changes = append(changes, name+" (known after apply)")
+		case !ok || !planned.Equal(prior):
+			changes = append(changes, name)
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
+
+// replaced returns the attributes whose change requires replacement.
+func (p *protocolPlan) replaced() []string {
+	var replaced []string
+	for _, attributePath := range p.RequiresReplace {
+		replaced = append(replaced, attributePath.String())
+	}
+	sort.Strings(replaced)
+
+	return replaced
+}
+
+func (h *protocolHarness) schema(typeName string) *tfprotov6.Schema {
+	schema, ok := h.schemas[typeName]
+	if !ok {
+		h.t.Fatalf("no schema for %s", typeName)
+	}
+
+	return schema
+}
+
+func (h *protocolHarness) dynamicValue(v tftypes.Value) *tfprotov6.DynamicValue {
+	h.t.Helper()
+
+	dv, err := tfprotov6.NewDynamicValue(v.Type(), v)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	return &dv
+}
+
+func (h *protocolHarness) value(typeName string, dv *tfprotov6.DynamicValue) tftypes.Value {
+	h.t.Helper()
+
+	typ := h.schema(typeName).ValueType()
+	if dv == nil {
+		return tftypes.NewValue(typ, nil)
+	}
+
+	v, err := dv.Unmarshal(typ)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	return v
+}
+
+func (h *protocolHarness) checkDiagnostics(operation string, diags []*tfprotov6.Diagnostic) {
+	h.t.Helper()
+
+	if err := diagnosticsError(diags); err != nil {
+		h.t.Fatalf("%s: %s", operation, err)
+	}
+}
+
+// diagnosticsError returns the error diagnostics as one error, or nil.
+func diagnosticsError(diags []*tfprotov6.Diagnostic) error {
+	var errs []string
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			errs = append(errs, fmt.Sprintf("%s: %s", d.Summary, d.Detail))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// blockValue returns the value of block with the attributes and nested blocks
+// in values. Other attributes are null, nested list and set blocks empty and
+// single blocks null, as Terraform decodes a configuration that omits them.
+func blockValue(block *tfprotov6.SchemaBlock, values map[string]tftypes.Value) tftypes.Value {
+	typ := block.ValueType().(tftypes.Object)
+
+	attrs := map[string]tftypes.Value{}
+	for _, a := range block.Attributes {
+		attrs[a.Name] = tftypes.NewValue(typ.AttributeTypes[a.Name], nil)
+	}
+	for _, b := range block.BlockTypes {
+		blockType := typ.AttributeTypes[b.TypeName]
+		switch b.Nesting {
+		case tfprotov6.SchemaNestedBlockNestingModeList, tfprotov6.SchemaNestedBlockNestingModeSet:
+			attrs[b.TypeName] = tftypes.NewValue(blockType, []tftypes.Value{})
+		default:
+			attrs[b.TypeName] = tftypes.NewValue(blockType, nil)
+		}
+	}
+	for name, v := range values {
+		attrs[name] = v
+	}
+
+	return tftypes.NewValue(typ, attrs)
+}
+
+// proposedNewState returns the state that Terraform proposes for config: the
+// configured values, and the prior values of computed attributes that the
+// configuration leaves unset.
+func proposedNewState(block *tfprotov6.SchemaBlock, prior tftypes.Value, config tftypes.Value) tftypes.Value {
+	if config.IsNull() {
+		return config
+	}
+
+	configured := map[string]tftypes.Value{}
+	_ = config.As(&configured)
+
+	// As shares the map of config
+	attrs := maps.Clone(configured)
+	for _, a := range block.Attributes {
+		if attrs[a.Name].IsNull() && a.Computed {
+			attrs[a.Name] = attributeValue(prior, a.Name)
+		}
+	}
+
+	return tftypes.NewValue(config.Type(), attrs)
+}
+
+// attributeNames returns the names of the attributes and nested blocks of
+// block.
+func attributeNames(block *tfprotov6.SchemaBlock) []string {
+	var names []string
+	for _, a := range block.Attributes {
+		names = append(names, a.Name)
+	}
+	for _, b := range block.BlockTypes {
+		names = append(names, b.TypeName)
+	}
+
+	return names
+}
+
+// attributeValue returns the name attribute of the object v, or a null value
+// when v is null.
+func attributeValue(v tftypes.Value, name string) tftypes.Value {
+	typ := v.Type().(tftypes.Object).AttributeTypes[name]
+	if v.IsNull() {
+		return tftypes.NewValue(typ, nil)
+	}
+
+	attrs := map[string]tftypes.Value{}
+	_ = v.As(&attrs)
+
+	return attrs[name]
+}
+
+// checkSameState fails unless the states want and got have the same
+// attributes, apart from ignore, as ImportStateVerify checks.
+func checkSameState(t *testing.T, want tftypes.Value, got tftypes.Value, ignore ...string) {
+	t.Helper()
+
+	wantAttrs, gotAttrs := map[string]tftypes.Value{}, map[string]tftypes.Value{}
+	_ = want.As(&wantAttrs)
+	_ = got.As(&gotAttrs)
+
+	for name, value := range wantAttrs {
+		if slices.Contains(ignore, name) {
+			continue
+		}
+		if !value.Equal(gotAttrs[name]) {
+			t.Errorf("%s: expected %s, got %s", name, value, gotAttrs[name])
+		}
+	}
+}
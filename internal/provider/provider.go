@@ -2,24 +2,82 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
 )
 
 var _ provider.Provider = &LimaProvider{}
 var _ provider.ProviderWithFunctions = &LimaProvider{}
 var _ provider.ProviderWithEphemeralResources = &LimaProvider{}
+var _ provider.ProviderWithListResources = &LimaProvider{}
 
 type LimaProvider struct {
 	version string
+
+	// transport runs limactl instead of the local or ssh transport the
+	// configuration selects, see withTransport.
+	transport limactl.Transport
+}
+
+// Option configures the providers New returns.
+type Option func(*LimaProvider)
+
+type LimaProviderModel struct {
+	LimactlPath types.String `tfsdk:"limactl_path"`
+	LimaHome    types.String `tfsdk:"lima_home"`
+	Env         types.Map    `tfsdk:"env"`
+	NamePrefix  types.String `tfsdk:"name_prefix"`
+	Workspace   types.String `tfsdk:"workspace"`
+
+	MaxParallelOperations types.Int64   `tfsdk:"max_parallel_operations"`
+	MaxMemoryOvercommit   types.Float64 `tfsdk:"max_memory_overcommit"`
+	ReadFromStore         types.Bool    `tfsdk:"read_from_store"`
+	DryRun                types.Bool    `tfsdk:"dry_run"`
+	AuditLogPath          types.String  `tfsdk:"audit_log_path"`
+	InterruptGracePeriod  types.String  `tfsdk:"interrupt_grace_period"`
+	LogCaptureDir         types.String  `tfsdk:"log_capture_dir"`
+
+	Defaults types.Object `tfsdk:"defaults"`
+
+	Retry *LimaProviderRetryModel `tfsdk:"retry"`
+	SSH   *LimaProviderSSHModel   `tfsdk:"ssh"`
 }
 
-type LimaProviderModel struct{}
+// defaultMaxParallelOperations caps concurrent instance boots when
+// max_parallel_operations is not set.
+const defaultMaxParallelOperations = 4
+
+// LimaProviderData is passed to resources through ProviderData.
+type LimaProviderData struct {
+	Client *limactl.Client
+
+	locks            *lockManager
+	capacity         *capacityPlanner
+	host             string
+	namePrefix       string
+	workspace        string
+	logCaptureDir    string
+	instanceDefaults map[string]attr.Value
+}
 
 func (p *LimaProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
 	resp.TypeName = "lima"
@@ -28,7 +86,60 @@ func (p *LimaProvider) Metadata(ctx context.Context, req provider.MetadataReques
 
 func (p *LimaProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Attributes: map[string]schema.Attribute{},
+		Attributes: map[string]schema.Attribute{
+			"limactl_path": schema.StringAttribute{
+				MarkdownDescription: "Path to the limactl binary. Defaults to 'limactl' looked up on PATH.",
+				Optional:            true,
+			},
+			"lima_home": schema.StringAttribute{
+				MarkdownDescription: "Lima home directory (LIMA_HOME) used for all instances and disks. Defaults to the LIMA_HOME environment variable, or '~/.lima'.",
+				Optional:            true,
+			},
+			"env": schema.MapAttribute{
+				MarkdownDescription: "Extra environment variables passed to every limactl invocation.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"name_prefix": schema.StringAttribute{
+				MarkdownDescription: "Prefix added to the limactl names of all `lima_instance` and `lima_disk` resources, for example `alice-`, so that people sharing a host do not collide. The `name` attributes and the disk references in `disks` blocks stay unprefixed. Changing it does not rename existing instances and disks.",
+				Optional:            true,
+			},
+			"workspace": schema.StringAttribute{
+				MarkdownDescription: "Identifies this configuration and workspace in the ownership markers written into the directories of new instances and disks, for example `\"infra/${terraform.workspace}\"`. Instances and disks whose marker names another workspace, or that have none, are only deleted with `force_delete`. Defaults to the user and the directory Terraform runs in, followed by the `" + workspaceEnvVar + "` environment variable if it is set, such as `alice@/home/alice/infra:staging`; set it when the same configuration is applied from several directories or machines.",
+				Optional:            true,
+			},
+			"max_parallel_operations": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Maximum number of instances booted at the same time. Operations on the same instance or disk are always serialized. Defaults to %d.", defaultMaxParallelOperations),
+				Optional:            true,
+			},
+			"max_memory_overcommit": schema.Float64Attribute{
+				MarkdownDescription: "Fail planning when the memory of the planned `lima_instance` resources, together with the running instances this configuration does not manage, exceeds this multiple of the host memory, for example `1.5`. Planning always warns when the instances need more CPUs or memory than the host has.",
+				Optional:            true,
+			},
+			"read_from_store": schema.BoolAttribute{
+				MarkdownDescription: "Read instances and disks directly from the LIMA_HOME directory instead of running `limactl list`, which makes refreshing many resources much faster. Changes still go through limactl. `_config/default.yaml` and `_config/override.yaml` are applied like Lima does, and unset CPUs and memory default to those Lima derives from the host. Falls back to limactl when the directory was written by an unrecognised Lima release or the host memory cannot be read. Defaults to false.",
+				Optional:            true,
+			},
+			"defaults": defaultsSchemaAttribute(),
+			"dry_run": schema.BoolAttribute{
+				MarkdownDescription: "Do not run any limactl command that creates, changes or deletes instances and disks. The commands are reported as warnings instead and the planned values are saved to state. Can also be enabled with the `" + dryRunEnvVar + "` environment variable.",
+				Optional:            true,
+			},
+			"audit_log_path": schema.StringAttribute{
+				MarkdownDescription: "File that every limactl invocation is appended to as a JSON line, with the resource, operation, command line, environment overrides, exit code, duration and the end of the output. The file is always written on the machine running Terraform.",
+				Optional:            true,
+			},
+			"interrupt_grace_period": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("How long a limactl command may take to exit after Terraform is interrupted, before it is killed. limactl receives SIGINT first so that it can release its locks and clean up. A Go duration such as `1m`. Defaults to `%s`.", limactl.DefaultInterruptGracePeriod),
+				Optional:            true,
+			},
+			"log_capture_dir": schema.StringAttribute{
+				MarkdownDescription: "Directory on the machine running Terraform where the host agent, serial console and cloud-init logs of an instance that fails to boot are saved before it is deleted. A subdirectory is created per failure. The end of each log is always included in the error.",
+				Optional:            true,
+			},
+			"retry": retrySchemaAttribute(),
+			"ssh":   sshSchemaAttribute(),
+		},
 	}
 }
 
@@ -40,6 +151,384 @@ func (p *LimaProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	if data.LimactlPath.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("limactl_path"),
+			"Unknown limactl path",
+			"The provider cannot run limactl because limactl_path is unknown. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.LimaHome.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("lima_home"),
+			"Unknown Lima home",
+			"The provider cannot locate Lima instances because lima_home is unknown. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.Env.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("env"),
+			"Unknown limactl environment",
+			"The provider cannot run limactl because env is unknown. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.NamePrefix.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name_prefix"),
+			"Unknown name_prefix",
+			"name_prefix must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.Workspace.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("workspace"),
+			"Unknown workspace",
+			"workspace must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.AuditLogPath.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("audit_log_path"),
+			"Unknown audit_log_path",
+			"audit_log_path must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.InterruptGracePeriod.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("interrupt_grace_period"),
+			"Unknown interrupt_grace_period",
+			"interrupt_grace_period must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.LogCaptureDir.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("log_capture_dir"),
+			"Unknown log_capture_dir",
+			"log_capture_dir must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.MaxParallelOperations.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_parallel_operations"),
+			"Unknown max_parallel_operations",
+			"max_parallel_operations must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if data.MaxMemoryOvercommit.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_memory_overcommit"),
+			"Unknown max_memory_overcommit",
+			"max_memory_overcommit must be known when the provider is configured. Set it to a static value or leave it unset.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	maxParallelOperations := int64(defaultMaxParallelOperations)
+	if !data.MaxParallelOperations.IsNull() {
+		maxParallelOperations = data.MaxParallelOperations.ValueInt64()
+	}
+
+	if maxParallelOperations < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_parallel_operations"),
+			"Invalid max_parallel_operations",
+			fmt.Sprintf("max_parallel_operations must be at least 1, got %d.", maxParallelOperations),
+		)
+		return
+	}
+
+	if !data.MaxMemoryOvercommit.IsNull() && data.MaxMemoryOvercommit.ValueFloat64() <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_memory_overcommit"),
+			"Invalid max_memory_overcommit",
+			fmt.Sprintf("max_memory_overcommit must be positive, got %g.", data.MaxMemoryOvercommit.ValueFloat64()),
+		)
+		return
+	}
+
+	namePrefix := data.NamePrefix.ValueString()
+	if namePrefix != "" && !namePrefixPattern.MatchString(namePrefix) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name_prefix"),
+			"Invalid name_prefix",
+			fmt.Sprintf("%q would make invalid Lima names. name_prefix must start with a letter or digit and contain only letters, digits, '.', '_' and '-'.", namePrefix),
+		)
+		return
+	}
+
+	workspace := data.Workspace.ValueString()
+	if workspace == "" {
+		workspace = defaultWorkspace()
+	}
+
+	gracePeriod := limactl.DefaultInterruptGracePeriod
+	if !data.InterruptGracePeriod.IsNull() {
+		var err error
+		gracePeriod, err = time.ParseDuration(data.InterruptGracePeriod.ValueString())
+		if err == nil && gracePeriod <= 0 {
+			err = errors.New("must be positive")
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("interrupt_grace_period"),
+				"Invalid interrupt_grace_period",
+				fmt.Sprintf("%q is not a valid duration: %s", data.InterruptGracePeriod.ValueString(), err),
+			)
+			return
+		}
+	}
+
+	instanceDefaults, diags := newInstanceDefaults(data.Defaults)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	retry, diags := newRetryPolicy(data.Retry)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var transport limactl.Transport
+	host := localHost
+	if data.SSH != nil {
+		sshTransport, diags := newSSHTransport(data.SSH)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if data.ReadFromStore.ValueBool() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("read_from_store"),
+				"Conflicting provider settings",
+				"read_from_store reads LIMA_HOME on the machine running Terraform and cannot be combined with ssh.",
+			)
+			return
+		}
+
+		transport = sshTransport
+		host = sshHostIdentity(data.SSH)
+	}
+	if p.transport != nil {
+		transport = p.transport
+	}
+
+	limactlPath := "limactl"
+	if !data.LimactlPath.IsNull() && data.LimactlPath.ValueString() != "" {
+		limactlPath = data.LimactlPath.ValueString()
+	}
+
+	// Remote paths are resolved by the remote shell.
+	if data.SSH == nil {
+		resolvedPath, err := exec.LookPath(limactlPath)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("limactl_path"),
+				"limactl not found",
+				fmt.Sprintf("Could not find limactl at %q: %s\nInstall Lima (https://lima-vm.io/) or set limactl_path.", limactlPath, err),
+			)
+			return
+		}
+
+		limactlPath = resolvedPath
+	}
+
+	limaHome := ""
+	if !data.LimaHome.IsNull() && data.LimaHome.ValueString() != "" {
+		limaHome = data.LimaHome.ValueString()
+
+		if data.SSH == nil {
+			var err error
+			limaHome, err = expandHome(limaHome)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("lima_home"),
+					"Invalid Lima home",
+					fmt.Sprintf("Could not expand %q: %s", data.LimaHome.ValueString(), err),
+				)
+				return
+			}
+		}
+
+		if !filepath.IsAbs(limaHome) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("lima_home"),
+				"Invalid Lima home",
+				fmt.Sprintf("lima_home must be an absolute path, got %q.", data.LimaHome.ValueString()),
+			)
+			return
+		}
+
+		// A missing directory is fine, limactl creates it on first use.
+		if data.SSH == nil {
+			if info, err := os.Stat(limaHome); err == nil && !info.IsDir() {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("lima_home"),
+					"Invalid Lima home",
+					fmt.Sprintf("lima_home %q exists but is not a directory.", limaHome),
+				)
+				return
+			}
+		}
+	}
+
+	env := map[string]string{}
+	if !data.Env.IsNull() {
+		resp.Diagnostics.Append(data.Env.ElementsAs(ctx, &env, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	for k := range env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("env"),
+				"Invalid environment variable name",
+				fmt.Sprintf("%q is not a valid environment variable name.", k),
+			)
+			return
+		}
+
+		if k == "LIMA_HOME" && limaHome != "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("env"),
+				"Conflicting LIMA_HOME",
+				"LIMA_HOME is set both in env and lima_home. Use lima_home only.",
+			)
+			return
+		}
+	}
+
+	dryRun := data.DryRun.ValueBool()
+	if data.DryRun.IsNull() {
+		if v, ok := os.LookupEnv(dryRunEnvVar); ok {
+			var err error
+			dryRun, err = strconv.ParseBool(v)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("dry_run"),
+					"Invalid "+dryRunEnvVar,
+					fmt.Sprintf("%s must be a boolean, got %q.", dryRunEnvVar, v),
+				)
+				return
+			}
+		}
+	}
+
+	var auditLog *limactl.AuditLog
+	if !data.AuditLogPath.IsNull() && data.AuditLogPath.ValueString() != "" {
+		auditLogPath, err := expandHome(data.AuditLogPath.ValueString())
+		if err == nil {
+			auditLog, err = limactl.OpenAuditLog(auditLogPath)
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log_path"),
+				"Failed to open audit log",
+				fmt.Sprintf("Could not open %q: %s", data.AuditLogPath.ValueString(), err),
+			)
+			return
+		}
+	}
+
+	logCaptureDir := ""
+	if !data.LogCaptureDir.IsNull() && data.LogCaptureDir.ValueString() != "" {
+		var err error
+		logCaptureDir, err = expandHome(data.LogCaptureDir.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("log_capture_dir"),
+				"Invalid log_capture_dir",
+				fmt.Sprintf("Could not expand %q: %s", data.LogCaptureDir.ValueString(), err),
+			)
+			return
+		}
+	}
+
+	clientConfig := limactl.Config{
+		Path:                 limactlPath,
+		LimaHome:             limaHome,
+		Env:                  env,
+		Transport:            transport,
+		InterruptGracePeriod: gracePeriod,
+		Retry:                retry,
+		DryRun:               dryRun,
+		AuditLog:             auditLog,
+		ReadFromStore:        data.ReadFromStore.ValueBool(),
+	}
+	client := limactl.New(clientConfig)
+
+	limactlVersion, err := client.DetectVersion(limactl.WithOperation(ctx, "", limactl.OperationConfigure))
+	if err != nil {
+		var cmdErr *limactl.CommandError
+		if limactl.Classify(err) == limactl.ErrorBinaryNotFound {
+			addLimactlError(&resp.Diagnostics, "Failed to detect limactl version", err, map[limactl.ErrorClass]path.Path{
+				limactl.ErrorBinaryNotFound: path.Root("limactl_path"),
+			})
+			return
+		}
+		if errors.As(err, &cmdErr) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("limactl_path"),
+				"Failed to detect limactl version",
+				err.Error(),
+			)
+			return
+		}
+
+		// Development builds do not report a release version. Assume they
+		// support everything rather than refusing to run.
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("limactl_path"),
+			"Unknown limactl version",
+			fmt.Sprintf("%s\nVersion checks are disabled. Lima %s or later is required.", err, limactl.MinimumVersion),
+		)
+	} else if limactlVersion.LessThan(limactl.MinimumVersion) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("limactl_path"),
+			"Unsupported limactl version",
+			fmt.Sprintf("limactl %s at %s is not supported. Upgrade Lima to %s or later.", limactlVersion, limactlPath, limactl.MinimumVersion),
+		)
+		return
+	}
+
+	providerData := &LimaProviderData{
+		Client:           client,
+		locks:            newLockManager(int(maxParallelOperations)),
+		capacity:         newCapacityPlanner(client, data.MaxMemoryOvercommit.ValueFloat64()),
+		host:             host,
+		namePrefix:       namePrefix,
+		workspace:        workspace,
+		logCaptureDir:    logCaptureDir,
+		instanceDefaults: instanceDefaults,
+	}
+
+	tflog.Debug(ctx, "Configured Lima provider", map[string]any{
+		"limactl_path":    limactlPath,
+		"limactl_version": fmt.Sprint(client.Version()),
+		"lima_home":       limaHome,
+		"dry_run":         dryRun,
+	})
+
+	resp.ResourceData = providerData
+	resp.DataSourceData = providerData
+	resp.ListResourceData = providerData
 }
 
 func (p *LimaProvider) Resources(ctx context.Context) []func() resource.Resource {
@@ -49,6 +538,13 @@ func (p *LimaProvider) Resources(ctx context.Context) []func() resource.Resource
 	}
 }
 
+func (p *LimaProvider) ListResources(ctx context.Context) []func() list.ListResource {
+	return []func() list.ListResource{
+		NewLimaInstanceListResource,
+		NewLimaDiskListResource,
+	}
+}
+
 func (p *LimaProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
 	return []func() ephemeral.EphemeralResource{}
 }
@@ -61,10 +557,29 @@ func (p *LimaProvider) Functions(ctx context.Context) []func() function.Function
 	return []func() function.Function{}
 }
 
-func New(version string) func() provider.Provider {
+// expandHome replaces a leading '~' with the current user's home directory.
+func expandHome(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(p, "~")), nil
+}
+
+func New(version string, options ...Option) func() provider.Provider {
 	return func() provider.Provider {
-		return &LimaProvider{
+		p := &LimaProvider{
 			version: version,
 		}
+		for _, option := range options {
+			option(p)
+		}
+
+		return p
 	}
 }
@@ -1,6 +1,8 @@
 package provider
 
 import (
+	"os"
+	"os/exec"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
@@ -28,3 +30,22 @@ func testAccPreCheck(t *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testUnitPreCheck skips unit tests that run against the fake limactl when no
+// Terraform CLI is available to drive them.
+func testUnitPreCheck(t *testing.T) {
+	t.Helper()
+
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") != "" || os.Getenv("TF_ACC_TERRAFORM_VERSION") != "" {
+		return
+	}
+
+	if _, err := exec.LookPath("terraform"); err != nil {
+		// CI installs Terraform, the tests must not be skipped there
+		if os.Getenv("CI") != "" {
+			t.Fatal("terraform not found on PATH in CI")
+		}
+
+		t.Skip("terraform not found on PATH, set TF_ACC_TERRAFORM_PATH to run unit tests against the fake limactl")
+	}
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// LimaProviderRetryModel configures retries of transient limactl failures.
+type LimaProviderRetryModel struct {
+	MaxAttempts  types.Int64  `tfsdk:"max_attempts"`
+	InitialDelay types.String `tfsdk:"initial_delay"`
+	MaxDelay     types.String `tfsdk:"max_delay"`
+}
+
+func retrySchemaAttribute() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Retry limactl commands that failed because another limactl process held a lock on the instance or disk, with exponential backoff. Only commands that are safe to repeat are retried, `limactl create` never is.",
+		Optional:            true,
+		Attributes: map[string]schema.Attribute{
+			"max_attempts": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Total number of attempts, 1 disables retries. Defaults to %d.", limactl.DefaultRetryPolicy.MaxAttempts),
+				Optional:            true,
+			},
+			"initial_delay": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Wait before the first retry, doubled for every further retry. Defaults to `%s`.", limactl.DefaultRetryPolicy.InitialDelay),
+				Optional:            true,
+			},
+			"max_delay": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Longest wait between two attempts. Defaults to `%s`.", limactl.DefaultRetryPolicy.MaxDelay),
+				Optional:            true,
+			},
+		},
+	}
+}
+
+// newRetryPolicy validates the retry block and builds the policy.
+func newRetryPolicy(data *LimaProviderRetryModel) (*limactl.RetryPolicy, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	retryPath := path.Root("retry")
+	policy := limactl.DefaultRetryPolicy
+
+	if data == nil {
+		return &policy, diags
+	}
+
+	if data.MaxAttempts.IsUnknown() || data.InitialDelay.IsUnknown() || data.MaxDelay.IsUnknown() {
+		diags.AddAttributeError(
+			retryPath,
+			"Unknown retry configuration",
+			"The retry block must be known when the provider is configured. Set it to static values.",
+		)
+		return nil, diags
+	}
+
+	if !data.MaxAttempts.IsNull() {
+		policy.MaxAttempts = int(data.MaxAttempts.ValueInt64())
+
+		if policy.MaxAttempts < 1 {
+			diags.AddAttributeError(
+				retryPath.AtName("max_attempts"),
+				"Invalid max_attempts",
+				fmt.Sprintf("max_attempts must be at least 1, got %d.", policy.MaxAttempts),
+			)
+			return nil, diags
+		}
+	}
+
+	for name, field := range map[string]struct {
+		value types.String
+		dst   *time.Duration
+	}{
+		"initial_delay": {data.InitialDelay, &policy.InitialDelay},
+		"max_delay":     {data.MaxDelay, &policy.MaxDelay},
+	} {
+		if field.value.IsNull() {
+			continue
+		}
+
+		d, err := time.ParseDuration(field.value.ValueString())
+		if err == nil && d < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			diags.AddAttributeError(
+				retryPath.AtName(name),
+				"Invalid "+name,
+				fmt.Sprintf("%q is not a valid duration: %s", field.value.ValueString(), err),
+			)
+			return nil, diags
+		}
+
+		*field.dst = d
+	}
+
+	return &policy, diags
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// LimaProviderSSHModel configures running limactl on a remote host.
+type LimaProviderSSHModel struct {
+	Host           types.String `tfsdk:"host"`
+	Port           types.Int64  `tfsdk:"port"`
+	User           types.String `tfsdk:"user"`
+	PrivateKey     types.String `tfsdk:"private_key"`
+	KnownHostsPath types.String `tfsdk:"known_hosts_path"`
+}
+
+func sshSchemaAttribute() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Run limactl on a remote host over SSH instead of locally. `limactl_path` and `lima_home` then refer to paths on the remote host.",
+		Optional:            true,
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				MarkdownDescription: "Host name or address of the remote host.",
+				Required:            true,
+			},
+			"port": schema.Int64Attribute{
+				MarkdownDescription: "SSH port. Defaults to 22.",
+				Optional:            true,
+			},
+			"user": schema.StringAttribute{
+				MarkdownDescription: "User to log in as. Defaults to the current user.",
+				Optional:            true,
+			},
+			"private_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key. Defaults to the keys of the SSH agent at SSH_AUTH_SOCK.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"known_hosts_path": schema.StringAttribute{
+				MarkdownDescription: "known_hosts file used to verify the host key. Defaults to '~/.ssh/known_hosts'.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+// newSSHTransport validates the ssh block and builds the transport.
+func newSSHTransport(data *LimaProviderSSHModel) (*limactl.SSHTransport, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	sshPath := path.Root("ssh")
+
+	if data.Host.IsUnknown() || data.Port.IsUnknown() || data.User.IsUnknown() || data.PrivateKey.IsUnknown() || data.KnownHostsPath.IsUnknown() {
+		diags.AddAttributeError(
+			sshPath,
+			"Unknown SSH configuration",
+			"The provider cannot connect to the remote host because part of the ssh block is unknown. Set it to static values.",
+		)
+		return nil, diags
+	}
+
+	config := limactl.SSHConfig{
+		Host:       data.Host.ValueString(),
+		Port:       int(data.Port.ValueInt64()),
+		User:       data.User.ValueString(),
+		PrivateKey: []byte(data.PrivateKey.ValueString()),
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		diags.AddAttributeError(
+			sshPath.AtName("port"),
+			"Invalid SSH port",
+			fmt.Sprintf("port must be between 1 and 65535, got %d.", config.Port),
+		)
+		return nil, diags
+	}
+
+	if config.User == "" {
+		current, err := user.Current()
+		if err != nil {
+			diags.AddAttributeError(
+				sshPath.AtName("user"),
+				"Unknown SSH user",
+				fmt.Sprintf("Could not determine the current user, set user explicitly: %s", err),
+			)
+			return nil, diags
+		}
+
+		config.User = current.Username
+	}
+
+	knownHosts := data.KnownHostsPath.ValueString()
+	if knownHosts == "" {
+		knownHosts = filepath.Join("~", ".ssh", "known_hosts")
+	}
+
+	knownHosts, err := expandHome(knownHosts)
+	if err == nil {
+		_, err = os.Stat(knownHosts)
+	}
+	if err != nil {
+		diags.AddAttributeError(
+			sshPath.AtName("known_hosts_path"),
+			"Invalid known_hosts file",
+			fmt.Sprintf("The host key of %s cannot be verified: %s", config.Host, err),
+		)
+		return nil, diags
+	}
+
+	config.KnownHostsPath = knownHosts
+
+	return limactl.NewSSHTransport(config), diags
+}
+
+// sshHostIdentity returns the host that identifies the Lima store of a remote
+// host, with the port when it is not the default.
+func sshHostIdentity(data *LimaProviderSSHModel) string {
+	port := data.Port.ValueInt64()
+	if port == 0 || port == 22 {
+		return data.Host.ValueString()
+	}
+
+	return net.JoinHostPort(data.Host.ValueString(), strconv.FormatInt(port, 10))
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// startLogLines is how much of each log file is included in a diagnostic.
+const startLogLines = 40
+
+// startFailureLogs reads the boot logs of an instance that failed to start,
+// before it is deleted. The logs are saved to log_capture_dir when set, and
+// their tails are returned formatted for the error diagnostic. Failures are
+// only logged, they must not hide the start error.
+func (d *LimaProviderData) startFailureLogs(ctx context.Context, name string) string {
+	inst, err := d.Client.GetInstance(ctx, name)
+	if err != nil {
+		tflog.Warn(ctx, "Could not locate instance to capture its logs", map[string]any{
+			"name":  name,
+			"error": err.Error(),
+		})
+		return ""
+	}
+
+	lines := startLogLines
+	if d.logCaptureDir != "" {
+		lines = 0
+	}
+
+	logs, err := d.Client.InstanceLogs(ctx, inst.Dir, lines)
+	if err != nil {
+		tflog.Warn(ctx, "Could not read instance logs", map[string]any{
+			"name":  name,
+			"error": err.Error(),
+		})
+		return ""
+	}
+
+	if len(logs) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nLogs from the instance directory, which is deleted with the instance:")
+
+	for _, log := range logs {
+		b.WriteString(formatLogTail(log))
+	}
+
+	if d.logCaptureDir != "" {
+		dir, err := saveInstanceLogs(d.logCaptureDir, name, logs)
+		if err != nil {
+			fmt.Fprintf(&b, "\n\nThe logs could not be saved to log_capture_dir: %s", err)
+		} else {
+			fmt.Fprintf(&b, "\n\nThe complete logs were saved to %s.", dir)
+		}
+	}
+
+	return b.String()
+}
+
+// formatLogTail formats the last startLogLines lines of log, with a header
+// naming the file and the number of lines included.
+func formatLogTail(log limactl.InstanceLog) string {
+	tail := limactl.LastLines(log.Content, startLogLines)
+	lines := countLines(tail)
+
+	if lines == countLines(log.Content) {
+		return fmt.Sprintf("\n\n--- %s (%d lines) ---\n%s", log.Name, lines, tail)
+	}
+
+	return fmt.Sprintf("\n\n--- %s (last %d lines) ---\n%s", log.Name, lines, tail)
+}
+
+// countLines returns the number of lines in s, with or without a final
+// newline.
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
+
+// saveInstanceLogs writes logs to a new directory below captureDir and
+// returns its path.
+func saveInstanceLogs(captureDir string, name string, logs []limactl.InstanceLog) (string, error) {
+	dir := filepath.Join(captureDir, name+"-"+time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	for _, log := range logs {
+		if err := os.WriteFile(filepath.Join(dir, log.Name), []byte(log.Content), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+func TestFormatLogTail(t *testing.T) {
+	var long strings.Builder
+	for i := 1; i <= startLogLines+10; i++ {
+		fmt.Fprintf(&long, "line %d\n", i)
+	}
+
+	tests := map[string]struct {
+		content string
+		header  string
+	}{
+		"short":          {content: "one\ntwo\nthree\n", header: "--- ha.stderr.log (3 lines) ---"},
+		"no final break": {content: "one\ntwo", header: "--- ha.stderr.log (2 lines) ---"},
+		"long":           {content: long.String(), header: fmt.Sprintf("--- ha.stderr.log (last %d lines) ---", startLogLines)},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := formatLogTail(limactl.InstanceLog{Name: "ha.stderr.log", Content: tt.content})
+			if !strings.Contains(got, tt.header) {
+				t.Errorf("expected header %q, got %q", tt.header, got)
+			}
+		})
+	}
+}
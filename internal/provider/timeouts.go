@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/michaelkosir/terraform-provider-lima/internal/limactl"
+)
+
+// Default operation timeouts. Instance creation is generous because the first
+// boot downloads an image and emulated architectures boot slowly.
+const (
+	defaultInstanceCreateTimeout = 20 * time.Minute
+	defaultInstanceReadTimeout   = 2 * time.Minute
+	defaultInstanceUpdateTimeout = 20 * time.Minute
+	defaultInstanceDeleteTimeout = 10 * time.Minute
+
+	defaultDiskCreateTimeout = 5 * time.Minute
+	defaultDiskReadTimeout   = 2 * time.Minute
+	defaultDiskUpdateTimeout = 5 * time.Minute
+	defaultDiskDeleteTimeout = 5 * time.Minute
+)
+
+// operationTimeout is the timeouts block value that applies to the running
+// operation.
+type operationTimeout struct {
+	// name is the timeouts attribute, such as "create".
+	name     string
+	duration time.Duration
+}
+
+// apply returns ctx bounded by the timeout.
+func (t operationTimeout) apply(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, t.duration)
+}
+
+// addError adds an error for a failed step of the operation. Failures caused
+// by the timeout get a summary of their own, so that they can be told apart
+// from limactl errors, which are classified with addLimactlError.
+func (t operationTimeout) addError(diags *diag.Diagnostics, summary string, err error, attributes map[limactl.ErrorClass]path.Path) {
+	if !timedOut(err) {
+		addLimactlError(diags, summary, err, attributes)
+		return
+	}
+
+	diags.AddError(
+		summary+": timed out",
+		fmt.Sprintf("The operation did not finish within the %s timeout of %s and limactl was interrupted. Increase `timeouts.%s` if it needs more time.\n\n%s", t.name, t.duration, t.name, err),
+	)
+}
+
+// timedOut reports whether err is the result of an operation timeout.
+func timedOut(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
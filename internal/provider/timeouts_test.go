@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestOperationTimeoutAddError(t *testing.T) {
+	timeout := operationTimeout{name: "create", duration: 20 * time.Minute}
+
+	var diags diag.Diagnostics
+	timeout.addError(&diags, "Failed to start Lima instance", errors.New("exit status 1"), nil)
+	timeout.addError(&diags, "Failed to start Lima instance", fmt.Errorf("interrupted: %w", context.DeadlineExceeded), nil)
+
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %d", len(diags))
+	}
+
+	if diags[0].Summary() != "Failed to start Lima instance" || diags[0].Detail() != "exit status 1" {
+		t.Errorf("unexpected diagnostic: %s: %s", diags[0].Summary(), diags[0].Detail())
+	}
+
+	if diags[1].Summary() != "Failed to start Lima instance: timed out" || !strings.Contains(diags[1].Detail(), "`timeouts.create`") || !strings.Contains(diags[1].Detail(), "20m0s") {
+		t.Errorf("unexpected diagnostic: %s: %s", diags[1].Summary(), diags[1].Detail())
+	}
+}